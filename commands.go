@@ -0,0 +1,319 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/clinotes/server/data"
+	"github.com/clinotes/server/route"
+)
+
+// command is a subcommand of the server binary
+type command struct {
+	Name  string
+	Args  string
+	Usage string
+	Run   func(flags *flag.FlagSet, args []string) error
+}
+
+// errUsage is returned by commands called with wrong arguments
+var errUsage = errors.New("Invalid arguments")
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"serve", "", "Run the API server, default without command", commandServe},
+		{"migrate", "", "Create the database structure and apply pending migrations", commandMigrate},
+		{"config check", "", "Print the configuration with secrets redacted and validate it", commandConfigCheck},
+		{"account list", "", "List all accounts", commandAccountList},
+		{"account create", "[-verified] <address>", "Create an account", commandAccountCreate},
+		{"account verify", "<address>", "Verify an account", commandAccountVerify},
+		{"account delete", "<address>", "Delete an account with all data, Stripe subscriptions are not canceled", commandAccountDelete},
+		{"token issue", "<address>", "Issue an access token and print it", commandTokenIssue},
+		{"token revoke", "<address> [id]", "Revoke an access token, or all without id", commandTokenRevoke},
+		{"notes export", "[-format json|csv|markdown] [-output file] <address>", "Export personal notes of an account", commandNotesExport},
+		{"help", "", "Show this help", commandHelp},
+	}
+}
+
+// run finds the command named by the first words of args and runs it
+func run(args []string) error {
+	if len(args) == 0 {
+		return commandServe(nil, nil)
+	}
+
+	for _, cmd := range commands {
+		words := strings.Fields(cmd.Name)
+		if len(args) < len(words) || strings.Join(args[:len(words)], " ") != cmd.Name {
+			continue
+		}
+
+		flags := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+		flags.Usage = func() {
+			fmt.Fprintf(os.Stderr, "Usage: server %s %s\n", cmd.Name, cmd.Args)
+			flags.PrintDefaults()
+		}
+
+		err := cmd.Run(flags, args[len(words):])
+		if err == errUsage {
+			flags.Usage()
+		}
+
+		return err
+	}
+
+	commandHelp(nil, nil)
+	return fmt.Errorf("Unknown command %q", strings.Join(args, " "))
+}
+
+func commandHelp(flags *flag.FlagSet, args []string) error {
+	fmt.Println("Usage: server <command> [arguments]")
+	fmt.Println()
+
+	for _, cmd := range commands {
+		fmt.Printf("  %-16s %s\n", cmd.Name, cmd.Usage)
+
+		if cmd.Args != "" {
+			fmt.Printf("  %-16s %s %s\n", "", cmd.Name, cmd.Args)
+		}
+	}
+
+	return nil
+}
+
+func commandServe(flags *flag.FlagSet, args []string) error {
+	return serve()
+}
+
+func commandConfigCheck(flags *flag.FlagSet, args []string) error {
+	conf.Print(os.Stdout)
+
+	if err := conf.Validate(); err != nil {
+		return err
+	}
+
+	fmt.Println("Configuration is valid")
+	return nil
+}
+
+func commandMigrate(flags *flag.FlagSet, args []string) error {
+	connect()
+
+	if err := data.Setup(); err != nil {
+		return err
+	}
+
+	fmt.Println("Database is up to date")
+	return nil
+}
+
+func commandAccountList(flags *flag.FlagSet, args []string) error {
+	connect()
+
+	list, err := data.AccountList()
+	if err != nil {
+		return err
+	}
+
+	for _, account := range list {
+		fmt.Printf("%d\t%s\t%s\tverified=%t\n", account.ID, account.Address, account.Created.Format("2006-01-02"), account.Verified)
+	}
+
+	return nil
+}
+
+func commandAccountCreate(flags *flag.FlagSet, args []string) error {
+	verified := flags.Bool("verified", false, "mark the account as verified")
+
+	address, err := parseAddressArg(flags, args)
+	if err != nil {
+		return err
+	}
+
+	address, err = data.ParseAddress(address)
+	if err != nil {
+		return err
+	}
+
+	connect()
+
+	account, err := data.AccountNew(address).Store()
+	if err != nil {
+		return err
+	}
+
+	if *verified {
+		if account, err = account.Verify(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Created account %d for %s\n", account.ID, account.Address)
+	return nil
+}
+
+func commandAccountVerify(flags *flag.FlagSet, args []string) error {
+	account, err := accountArg(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if _, err = account.Verify(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Verified account %s\n", account.Address)
+	return nil
+}
+
+func commandAccountDelete(flags *flag.FlagSet, args []string) error {
+	account, err := accountArg(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if err = account.Delete(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Deleted account %s\n", account.Address)
+	return nil
+}
+
+func commandTokenIssue(flags *flag.FlagSet, args []string) error {
+	account, err := accountArg(flags, args)
+	if err != nil {
+		return err
+	}
+
+	token := data.TokenNew(account.ID, data.TokenTypeAccess)
+	tokenRaw := token.Raw()
+
+	if token, err = token.Store(); err != nil {
+		return err
+	}
+
+	fmt.Printf("%d\t%s\n", token.ID, tokenRaw)
+	return nil
+}
+
+func commandTokenRevoke(flags *flag.FlagSet, args []string) error {
+	if err := flags.Parse(args); err != nil || flags.NArg() < 1 || flags.NArg() > 2 {
+		return errUsage
+	}
+
+	id := 0
+	if flags.NArg() == 2 {
+		var err error
+		if id, err = strconv.Atoi(flags.Arg(1)); err != nil {
+			return errUsage
+		}
+	}
+
+	connect()
+
+	account, err := data.AccountByAddress(flags.Arg(0))
+	if err != nil {
+		return errors.New("Unknown account address")
+	}
+
+	tokens, err := account.GetTokenList(data.TokenTypeAccess)
+	if err != nil {
+		return err
+	}
+
+	revoked := 0
+	for _, token := range tokens {
+		if id != 0 && token.ID != id {
+			continue
+		}
+
+		if err = token.Remove(); err != nil {
+			return err
+		}
+
+		revoked++
+	}
+
+	if id != 0 && revoked == 0 {
+		return errors.New("Unknown token")
+	}
+
+	fmt.Printf("Revoked %d tokens of %s\n", revoked, account.Address)
+	return nil
+}
+
+func commandNotesExport(flags *flag.FlagSet, args []string) error {
+	format := flags.String("format", "json", "export format: json, csv or markdown")
+	output := flags.String("output", "", "file to write, standard output if empty")
+
+	account, err := accountArg(flags, args)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+
+		defer file.Close()
+		w = file
+	}
+
+	return route.ExportNotes(context.Background(), w, *format, account.ID)
+}
+
+// parseAddressArg parses flags and returns the single address argument
+func parseAddressArg(flags *flag.FlagSet, args []string) (string, error) {
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		return "", errUsage
+	}
+
+	return flags.Arg(0), nil
+}
+
+// accountArg parses flags and retrieves the Account of the single address
+// argument
+func accountArg(flags *flag.FlagSet, args []string) (*data.Account, error) {
+	address, err := parseAddressArg(flags, args)
+	if err != nil {
+		return nil, err
+	}
+
+	connect()
+
+	account, err := data.AccountByAddress(address)
+	if err != nil {
+		return nil, errors.New("Unknown account address")
+	}
+
+	return account, nil
+}
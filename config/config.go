@@ -0,0 +1,257 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/clinotes/server/logger"
+	"github.com/spf13/viper"
+)
+
+// Config is the configuration of the server, every field is read from the
+// environment variable named in its tag or the same key in lower case from
+// an optional YAML or TOML file, the environment takes precedence
+type Config struct {
+	DatabaseURL      string `config:"DATABASE_URL" secret:"true"`
+	MaxDBConnections int    `config:"MAX_DB_CONNECTIONS"`
+
+	Env      string `config:"ENV"`
+	Port     string `config:"PORT"`
+	LogLevel string `config:"LOG_LEVEL"`
+
+	PostmarkAPIKey  string `config:"POSTMARK_API_KEY" secret:"true"`
+	PostmarkFrom    string `config:"POSTMARK_FROM"`
+	PostmarkReplyTo string `config:"POSTMARK_REPLY_TO"`
+
+	TemplateWelcome int64 `config:"POSTMARK_TEMPLATE_WELCOME"`
+	TemplateConfirm int64 `config:"POSTMARK_TEMPLATE_CONFIRM"`
+	TemplateToken   int64 `config:"POSTMARK_TEMPLATE_TOKEN"`
+	TemplateInvite  int64 `config:"POSTMARK_TEMPLATE_INVITE"`
+	TemplateDelete  int64 `config:"POSTMARK_TEMPLATE_DELETE"`
+	TemplateGoodbye int64 `config:"POSTMARK_TEMPLATE_GOODBYE"`
+	TemplateEmail   int64 `config:"POSTMARK_TEMPLATE_EMAIL"`
+	TemplateNotice  int64 `config:"POSTMARK_TEMPLATE_NOTICE"`
+	TemplateTrial   int64 `config:"POSTMARK_TEMPLATE_TRIAL"`
+	TemplateGrace   int64 `config:"POSTMARK_TEMPLATE_GRACE"`
+
+	BlockedDomainsFile string `config:"BLOCKED_DOMAINS_FILE"`
+	AdminToken         string `config:"ADMIN_TOKEN" secret:"true"`
+
+	StripeAPIKey   string `config:"STRIPE_API_KEY" secret:"true"`
+	TrialDays      int    `config:"TRIAL_DAYS"`
+	GraceDays      int    `config:"GRACE_DAYS"`
+	UnverifiedDays int    `config:"UNVERIFIED_DAYS"`
+
+	ReadTimeout     int `config:"READ_TIMEOUT"`
+	WriteTimeout    int `config:"WRITE_TIMEOUT"`
+	IdleTimeout     int `config:"IDLE_TIMEOUT"`
+	ShutdownTimeout int `config:"SHUTDOWN_TIMEOUT"`
+	RequestTimeout  int `config:"REQUEST_TIMEOUT"`
+	TransferTimeout int `config:"TRANSFER_TIMEOUT"`
+}
+
+// Errors lists all problems found by Validate
+type Errors []string
+
+func (e Errors) Error() string {
+	return "Invalid configuration:\n  " + strings.Join(e, "\n  ")
+}
+
+// Load reads Config from the environment and file, which is skipped when
+// empty
+func Load(file string) (*Config, error) {
+	v := viper.New()
+	v.AutomaticEnv()
+
+	if file != "" {
+		v.SetConfigFile(file)
+
+		if err := v.ReadInConfig(); err != nil {
+			return nil, err
+		}
+	}
+
+	var conf Config
+	value := reflect.ValueOf(&conf).Elem()
+
+	for i := 0; i < value.NumField(); i++ {
+		key := value.Type().Field(i).Tag.Get("config")
+		field := value.Field(i)
+
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(v.GetString(key))
+		case reflect.Int, reflect.Int64:
+			field.SetInt(v.GetInt64(key))
+		}
+	}
+
+	return &conf, nil
+}
+
+// Validate checks Config and reports all problems at once
+func (c Config) Validate() error {
+	var errs Errors
+
+	required := func(name string, value string) {
+		if value == "" {
+			errs = append(errs, "Please set "+name)
+		}
+	}
+
+	positive := func(name string, value int64) {
+		if value <= 0 {
+			errs = append(errs, "Please set "+name+" > 0")
+		}
+	}
+
+	required("DATABASE_URL", c.DatabaseURL)
+	required("POSTMARK_API_KEY", c.PostmarkAPIKey)
+	required("POSTMARK_FROM", c.PostmarkFrom)
+	required("POSTMARK_REPLY_TO", c.PostmarkReplyTo)
+
+	if c.Env != "local" {
+		required("PORT", c.Port)
+	}
+
+	positive("POSTMARK_TEMPLATE_WELCOME", c.TemplateWelcome)
+	positive("POSTMARK_TEMPLATE_CONFIRM", c.TemplateConfirm)
+	positive("POSTMARK_TEMPLATE_TOKEN", c.TemplateToken)
+
+	if c.TrialDays > 0 {
+		positive("POSTMARK_TEMPLATE_TRIAL", c.TemplateTrial)
+	}
+
+	if c.GraceDays > 0 {
+		positive("POSTMARK_TEMPLATE_GRACE", c.TemplateGrace)
+	}
+
+	if _, err := logger.ParseLevel(c.LogLevel); err != nil {
+		errs = append(errs, "Please set LOG_LEVEL to debug, info, warn or error")
+	}
+
+	if c.MaxDBConnections < 0 {
+		errs = append(errs, "Please set MAX_DB_CONNECTIONS >= 0")
+	}
+
+	if c.TrialDays < 0 || c.GraceDays < 0 || c.UnverifiedDays < 0 {
+		errs = append(errs, "Please set TRIAL_DAYS, GRACE_DAYS and UNVERIFIED_DAYS >= 0")
+	}
+
+	if c.ReadTimeout < 0 || c.WriteTimeout < 0 || c.IdleTimeout < 0 || c.ShutdownTimeout < 0 || c.RequestTimeout < 0 || c.TransferTimeout < 0 {
+		errs = append(errs, "Please set READ_TIMEOUT, WRITE_TIMEOUT, IDLE_TIMEOUT, SHUTDOWN_TIMEOUT, REQUEST_TIMEOUT and TRANSFER_TIMEOUT >= 0")
+	}
+
+	if errs != nil {
+		return errs
+	}
+
+	return nil
+}
+
+// Address returns the host and port to listen on, the local environment
+// uses localhost to avoid annoying MacOS security warnings
+func (c Config) Address() string {
+	if c.Env == "local" {
+		return "localhost:8000"
+	}
+
+	return ":" + c.Port
+}
+
+// TrialPeriod returns the length of free trials
+func (c Config) TrialPeriod() time.Duration {
+	return days(c.TrialDays)
+}
+
+// GracePeriod returns the time paid accounts keep access after a failed
+// payment
+func (c Config) GracePeriod() time.Duration {
+	return days(c.GraceDays)
+}
+
+// UnverifiedPeriod returns the time before unverified accounts are removed
+func (c Config) UnverifiedPeriod() time.Duration {
+	return days(c.UnverifiedDays)
+}
+
+// Timeouts returns the read, write and idle timeouts of the HTTP server,
+// unset values fall back to 10s, 30s and 120s
+func (c Config) Timeouts() (read time.Duration, write time.Duration, idle time.Duration) {
+	return seconds(c.ReadTimeout, 10), seconds(c.WriteTimeout, 30), seconds(c.IdleTimeout, 120)
+}
+
+// ShutdownPeriod returns the time running requests get to finish on
+// shutdown, unset it falls back to 25s to stay below Heroku's 30s limit
+func (c Config) ShutdownPeriod() time.Duration {
+	return seconds(c.ShutdownTimeout, 25)
+}
+
+// Level returns the lowest level logged, info when LOG_LEVEL is not set
+func (c Config) Level() logger.Level {
+	level, _ := logger.ParseLevel(c.LogLevel)
+
+	return level
+}
+
+// RequestDeadline returns the time requests and their queries may take,
+// unset it falls back to 20s to answer before WRITE_TIMEOUT
+func (c Config) RequestDeadline() time.Duration {
+	return seconds(c.RequestTimeout, 20)
+}
+
+// TransferDeadline returns the time streamed downloads and uploads may take
+// instead of REQUEST_TIMEOUT and the read and write timeouts, unset it falls
+// back to 10 minutes
+func (c Config) TransferDeadline() time.Duration {
+	return seconds(c.TransferTimeout, 600)
+}
+
+// Print writes Config to w with secrets redacted
+func (c Config) Print(w io.Writer) {
+	value := reflect.ValueOf(c)
+
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		text := fmt.Sprint(value.Field(i).Interface())
+
+		if field.Tag.Get("secret") == "true" && text != "" {
+			text = "[redacted]"
+		}
+
+		fmt.Fprintf(w, "%s=%s\n", field.Tag.Get("config"), text)
+	}
+}
+
+func days(n int) time.Duration {
+	return time.Duration(n) * 24 * time.Hour
+}
+
+func seconds(n int, fallback int) time.Duration {
+	if n == 0 {
+		n = fallback
+	}
+
+	return time.Duration(n) * time.Second
+}
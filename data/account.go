@@ -19,24 +19,42 @@
 package data
 
 import (
+	"context"
 	"errors"
+	"net/mail"
+	"strings"
 	"time"
+
+	"github.com/jmoiron/sqlx"
 )
 
 // AccountInterface defines Account
 type AccountInterface interface {
+	Delete() error
+	DeleteContext(ctx context.Context) error
+	GetSoleOrganizations() ([]*Organization, error)
+	GetSoleOrganizationsContext(ctx context.Context) ([]*Organization, error)
 	GetSubscription() *Subscription
+	GetSubscriptionContext(ctx context.Context) *Subscription
 	GetToken(t string, tokenType int) (*Token, error)
-	GetTokenList(tokenType int) []*Token
+	GetTokenContext(ctx context.Context, t string, tokenType int) (*Token, error)
+	GetTokenList(tokenType int) ([]*Token, error)
+	GetTokenListContext(ctx context.Context, tokenType int) ([]*Token, error)
 	HasSubscription() bool
+	HasSubscriptionContext(ctx context.Context) bool
 	IsStored() bool
 	Refresh() (*Account, error)
+	RefreshContext(ctx context.Context) (*Account, error)
 	Remove() error
+	RemoveContext(ctx context.Context) error
 	Store() (*Account, error)
+	StoreContext(ctx context.Context) (*Account, error)
+	StoreTx(tx *sqlx.Tx) (*Account, error)
 	Verify() (*Account, error)
+	VerifyContext(ctx context.Context) (*Account, error)
 
-	create() (*Account, error)
-	update() (*Account, error)
+	create(c conn) (*Account, error)
+	update(c conn) (*Account, error)
 }
 
 // Account implements AccountInterface
@@ -45,58 +63,227 @@ type Account struct {
 	Address  string    `db:"address"`
 	Created  time.Time `db:"created"`
 	Verified bool      `db:"verified"`
+	Customer string    `db:"customer"`
 }
 
+const accountColumns = "id, address, created, verified, COALESCE(customer, '') AS customer"
+
+var (
+	// ErrAddressInvalid is returned for malformed addresses
+	ErrAddressInvalid = errors.New("Invalid address")
+	// ErrAddressBlocked is returned for addresses of blocked domains
+	ErrAddressBlocked = errors.New("Address domain not allowed")
+	// ErrTokenNotFound is returned when no Token of Account matches
+	ErrTokenNotFound = errors.New("Token not found")
+	// ErrOrganizationOwner is returned when deleting the last owner of an
+	// Organization with other members
+	ErrOrganizationOwner = errors.New("Account is the last owner of an organization with other members")
+
+	blockedDomains = map[string]bool{}
+)
+
 // AccountNew creates a new account
 func AccountNew(address string) *Account {
-	return &Account{0, address, time.Now(), false}
+	return &Account{0, NormalizeAddress(address), time.Now(), false, ""}
 }
 
-// AccountByAddress retrieves Account by address
+// AccountByAddress retrieves Account by address, ignoring case
 func AccountByAddress(address string) (*Account, error) {
+	return AccountByAddressContext(context.Background(), address)
+}
+
+// AccountByAddressContext is AccountByAddress bound to ctx
+func AccountByAddressContext(ctx context.Context, address string) (*Account, error) {
 	var account Account
 
-	err := db.Get(&account, "SELECT id, address, created, verified FROM account WHERE address = $1", address)
+	err := with(ctx).get(&account, "SELECT "+accountColumns+" FROM account WHERE lower(address) = $1", NormalizeAddress(address))
 
 	return &account, err
 }
 
 // AccountByID retrieves Account by id
 func AccountByID(id int) (*Account, error) {
+	return AccountByIDContext(context.Background(), id)
+}
+
+// AccountByIDContext is AccountByID bound to ctx
+func AccountByIDContext(ctx context.Context, id int) (*Account, error) {
+	return accountByID(with(ctx), id)
+}
+
+func accountByID(c conn, id int) (*Account, error) {
 	var account Account
 
-	err := db.Get(&account, "SELECT id, address, created, verified FROM account WHERE id = $1", id)
+	err := c.get(&account, "SELECT "+accountColumns+" FROM account WHERE id = $1", id)
 
 	return &account, err
 }
 
+// AccountList retrieves all Account
+func AccountList() ([]*Account, error) {
+	return AccountListContext(context.Background())
+}
+
+// AccountListContext is AccountList bound to ctx
+func AccountListContext(ctx context.Context) ([]*Account, error) {
+	var list []*Account
+
+	err := with(ctx).selectAll(&list, "SELECT "+accountColumns+" FROM account ORDER BY id ASC")
+
+	return list, err
+}
+
+// AccountListUnverified retrieves all Account not verified and without
+// maintenance Token since before, resending the verification keeps them
+func AccountListUnverified(before time.Time) ([]*Account, error) {
+	return AccountListUnverifiedContext(context.Background(), before)
+}
+
+// AccountListUnverifiedContext is AccountListUnverified bound to ctx
+func AccountListUnverifiedContext(ctx context.Context, before time.Time) ([]*Account, error) {
+	var list []*Account
+
+	err := with(ctx).selectAll(&list, `SELECT `+accountColumns+`
+		FROM account WHERE verified = FALSE AND created < $1 AND NOT EXISTS (
+			SELECT 1 FROM token WHERE token.account = account.id AND token.type = $2 AND token.created >= $1
+		) ORDER BY id ASC`, before, TokenTypeMaintenace)
+
+	return list, err
+}
+
+// BlockDomains rejects addresses of domains and their subdomains in
+// ParseAddress, e.g. for disposable mail providers
+func BlockDomains(domains []string) {
+	blockedDomains = map[string]bool{}
+
+	for _, domain := range domains {
+		if domain = NormalizeAddress(domain); domain != "" {
+			blockedDomains[domain] = true
+		}
+	}
+}
+
+// NormalizeAddress returns address in the form stored for an Account
+func NormalizeAddress(address string) string {
+	return strings.ToLower(strings.TrimSpace(address))
+}
+
+// ParseAddress validates a plain RFC 5322 address and returns it normalized
+func ParseAddress(address string) (string, error) {
+	normalized := NormalizeAddress(address)
+
+	parsed, err := mail.ParseAddress(normalized)
+	if err != nil || parsed.Name != "" || parsed.Address != normalized {
+		return "", ErrAddressInvalid
+	}
+
+	domain := normalized[strings.LastIndex(normalized, "@")+1:]
+	for domain != "" {
+		if blockedDomains[domain] {
+			return "", ErrAddressBlocked
+		}
+
+		i := strings.Index(domain, ".")
+		if i < 0 {
+			break
+		}
+
+		domain = domain[i+1:]
+	}
+
+	return normalized, nil
+}
+
 // GetToken retrieves Token for Account
 func (a Account) GetToken(t string, tokenType int) (*Token, error) {
-	token := &Token{}
-	found := false
+	return a.GetTokenContext(context.Background(), t, tokenType)
+}
+
+// GetTokenContext is GetToken bound to ctx
+func (a Account) GetTokenContext(ctx context.Context, t string, tokenType int) (*Token, error) {
+	list, err := a.GetTokenListContext(ctx, tokenType)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, item := range a.GetTokenList(tokenType) {
+	for _, item := range list {
 		if item.Matches(t) {
-			found = true
-			token = item
+			return item, nil
 		}
 	}
 
-	if found {
-		return token, nil
+	return nil, ErrTokenNotFound
+}
+
+// GetSoleOrganizations retrieves the Organization only used by Account,
+// which are removed with it, and fails with ErrOrganizationOwner when
+// Account is the last owner of an Organization with other members
+func (a Account) GetSoleOrganizations() ([]*Organization, error) {
+	return a.GetSoleOrganizationsContext(context.Background())
+}
+
+// GetSoleOrganizationsContext is GetSoleOrganizations bound to ctx
+func (a Account) GetSoleOrganizationsContext(ctx context.Context) ([]*Organization, error) {
+	memberships, err := MemberListByAccountContext(ctx, a.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []*Organization
+	for _, membership := range memberships {
+		if membership.Role != RoleOwner {
+			continue
+		}
+
+		members, err := MemberListByOrganizationContext(ctx, membership.Organization)
+		if err != nil {
+			return nil, err
+		}
+
+		owned := true
+		for _, member := range members {
+			if member.ID != membership.ID && member.Role == RoleOwner {
+				owned = false
+			}
+		}
+
+		if !owned {
+			continue
+		}
+
+		if len(members) > 1 {
+			return nil, ErrOrganizationOwner
+		}
+
+		org, err := OrganizationByIDContext(ctx, membership.Organization)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, org)
 	}
 
-	return nil, errors.New("Token not found")
+	return list, nil
 }
 
 // GetTokenList retrieves all Token for Account
-func (a Account) GetTokenList(tokenType int) []*Token {
-	return TokenListByAccountAndType(a.ID, tokenType)
+func (a Account) GetTokenList(tokenType int) ([]*Token, error) {
+	return a.GetTokenListContext(context.Background(), tokenType)
+}
+
+// GetTokenListContext is GetTokenList bound to ctx
+func (a Account) GetTokenListContext(ctx context.Context, tokenType int) ([]*Token, error) {
+	return TokenListByAccountAndTypeContext(ctx, a.ID, tokenType)
 }
 
 // GetSubscription retrieves Account Subscription
 func (a Account) GetSubscription() *Subscription {
-	sub, err := SubscriptionByAccountID(a.ID)
+	return a.GetSubscriptionContext(context.Background())
+}
+
+// GetSubscriptionContext is GetSubscription bound to ctx
+func (a Account) GetSubscriptionContext(ctx context.Context) *Subscription {
+	sub, err := SubscriptionByAccountIDContext(ctx, a.ID)
 
 	if err == nil {
 		return sub
@@ -105,9 +292,17 @@ func (a Account) GetSubscription() *Subscription {
 	return nil
 }
 
-// HasSubscription checks if Account has a Subscription
+// HasSubscription checks if Account has a valid Subscription, including
+// running trials and grace periods after failed payments
 func (a Account) HasSubscription() bool {
-	return a.GetSubscription() != nil
+	return a.HasSubscriptionContext(context.Background())
+}
+
+// HasSubscriptionContext is HasSubscription bound to ctx
+func (a Account) HasSubscriptionContext(ctx context.Context) bool {
+	sub := a.GetSubscriptionContext(ctx)
+
+	return sub != nil && sub.IsValid()
 }
 
 // IsStored checks if Account is stored in DB
@@ -117,53 +312,123 @@ func (a Account) IsStored() bool {
 
 // Refresh Account from DB
 func (a Account) Refresh() (*Account, error) {
-	return AccountByID(a.ID)
+	return a.RefreshContext(context.Background())
+}
+
+// RefreshContext is Refresh bound to ctx
+func (a Account) RefreshContext(ctx context.Context) (*Account, error) {
+	return AccountByIDContext(ctx, a.ID)
 }
 
-// Remove Account
+// Remove Account and all Invitation sent to its address
 func (a Account) Remove() error {
-	_, err := db.Exec("delete FROM account WHERE id = $1", a.ID)
+	return a.RemoveContext(context.Background())
+}
+
+// RemoveContext is Remove bound to ctx
+func (a Account) RemoveContext(ctx context.Context) error {
+	return TransactionContext(ctx, func(tx *sqlx.Tx) error {
+		c := withTx(tx)
+
+		// Notes of organizations are handed over to a remaining member,
+		// owners first, instead of being removed with their author
+		_, err := c.exec(`UPDATE note SET account = (
+				SELECT account FROM member
+				WHERE member.organization = note.organization AND member.account <> $1
+				ORDER BY role ASC, id ASC LIMIT 1
+			) WHERE account = $1 AND organization IS NOT NULL AND EXISTS (
+				SELECT 1 FROM member
+				WHERE member.organization = note.organization AND member.account <> $1
+			)`, a.ID)
+
+		if err != nil {
+			return err
+		}
 
-	return err
+		_, err = c.exec(`WITH invitations AS (
+			DELETE FROM invitation WHERE address = (SELECT address FROM account WHERE id = $1)
+		) delete FROM account WHERE id = $1`, a.ID)
+
+		return err
+	})
+}
+
+// Delete removes Account with the Organization only used by it, unlike
+// Remove it keeps organizations with other members from losing their owner
+func (a Account) Delete() error {
+	return a.DeleteContext(context.Background())
+}
+
+// DeleteContext is Delete bound to ctx
+func (a Account) DeleteContext(ctx context.Context) error {
+	list, err := a.GetSoleOrganizationsContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, org := range list {
+		if err = org.RemoveContext(ctx); err != nil {
+			return err
+		}
+	}
+
+	return a.RemoveContext(ctx)
 }
 
 // Store writes Account to DB
 func (a Account) Store() (*Account, error) {
-	if a.IsStored() {
-		return a.update()
-	}
+	return a.StoreContext(context.Background())
+}
+
+// StoreContext is Store bound to ctx
+func (a Account) StoreContext(ctx context.Context) (*Account, error) {
+	return a.store(with(ctx))
+}
 
-	return a.create()
+// StoreTx writes Account to DB as part of transaction tx
+func (a Account) StoreTx(tx *sqlx.Tx) (*Account, error) {
+	return a.store(withTx(tx))
 }
 
 // Verify verifies Account and updates the DB
 func (a Account) Verify() (*Account, error) {
+	return a.VerifyContext(context.Background())
+}
+
+// VerifyContext is Verify bound to ctx
+func (a Account) VerifyContext(ctx context.Context) (*Account, error) {
 	a.Verified = true
 
-	return a.update()
+	return a.update(with(ctx))
 }
 
-func (a Account) create() (*Account, error) {
-	var id int
-	rows, err := db.Query("insert into account (address) values($1)", a.Address)
+func (a Account) store(c conn) (*Account, error) {
+	if a.IsStored() {
+		return a.update(c)
+	}
+
+	return a.create(c)
+}
+
+func (a Account) create(c conn) (*Account, error) {
+	var account Account
+	err := c.get(&account, "insert into account (address) values($1) RETURNING "+accountColumns, a.Address)
 
 	if err != nil {
 		return nil, err
 	}
 
-	rows.Next()
-	rows.Scan(&id)
-
-	return AccountByAddress(a.Address)
+	return &account, nil
 }
 
-func (a Account) update() (*Account, error) {
-	_, err := db.Query(`UPDATE account SET verified = $2
-		WHERE id = $1`, a.ID, a.Verified)
+func (a Account) update(c conn) (*Account, error) {
+	var account Account
+	err := c.get(&account, `UPDATE account SET verified = $2, customer = NULLIF($3, '')
+		WHERE id = $1 RETURNING `+accountColumns, a.ID, a.Verified, a.Customer)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return &a, nil
+	return &account, nil
 }
@@ -20,10 +20,65 @@ package data
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
+func TestNormalizeAddress(t *testing.T) {
+	assert.Equal(t, "lorem@example.com", NormalizeAddress(" Lorem@Example.COM "))
+	assert.Equal(t, "", NormalizeAddress("  "))
+}
+
+func TestParseAddress(t *testing.T) {
+	address, err := ParseAddress(" Foo@Example.com")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "foo@example.com", address)
+
+	for _, invalid := range []string{"", "foo", "foo@", "@example.com", "Foo <foo@example.com>", "foo@example.com, bar@example.com"} {
+		_, err = ParseAddress(invalid)
+		assert.Equal(t, ErrAddressInvalid, err, invalid)
+	}
+
+	BlockDomains([]string{"Disposable.example"})
+
+	_, err = ParseAddress("foo@disposable.example")
+	assert.Equal(t, ErrAddressBlocked, err)
+
+	_, err = ParseAddress("foo@mail.disposable.example")
+	assert.Equal(t, ErrAddressBlocked, err)
+
+	_, err = ParseAddress("foo@notdisposable.example")
+	assert.Nil(t, err)
+
+	BlockDomains(nil)
+
+	_, err = ParseAddress("foo@disposable.example")
+	assert.Nil(t, err)
+}
+
+func TestAccountAddressCase(t *testing.T) {
+	acc := AccountNew("Case@Example.com")
+
+	assert.Equal(t, "case@example.com", acc.Address)
+
+	acc, err := acc.Store()
+
+	if assert.Nil(t, err) {
+		acc2, err2 := AccountByAddress("CASE@example.COM")
+
+		if assert.Nil(t, err2) {
+			assert.Equal(t, acc.ID, acc2.ID)
+		}
+
+		_, err = AccountNew("case@EXAMPLE.com").Store()
+		assert.NotNil(t, err)
+
+		acc.Remove()
+	}
+}
+
 func TestAccount(t *testing.T) {
 	acc := AccountNew("lorem@example.com")
 
@@ -69,14 +124,171 @@ func TestAccount(t *testing.T) {
 		assert.Equal(t, acc.Address, "lorem@example.com")
 		assert.True(t, acc.Verified)
 
-		assert.Equal(t, 0, len(acc.GetTokenList(TokenTypeAccess)))
-		assert.Equal(t, 0, len(acc.GetTokenList(TokenTypeMaintenace)))
+		assert.Equal(t, "", acc.Customer)
+		acc.Customer = "cus_test"
+		acc, err = acc.Store()
+
+		if assert.Nil(t, err) {
+			acc4, err4 := acc.Refresh()
+
+			assert.Nil(t, err4)
+			assert.Equal(t, "cus_test", acc4.Customer)
+			assert.True(t, acc4.Verified)
+		}
+
+		tokens, err := acc.GetTokenList(TokenTypeAccess)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(tokens))
+
+		tokens, err = acc.GetTokenList(TokenTypeMaintenace)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(tokens))
 
 		tok, err := acc.GetToken("", TokenTypeAccess)
 
 		assert.Nil(t, tok)
-		assert.NotNil(t, err)
+		assert.Equal(t, ErrTokenNotFound, err)
 	}
 
 	acc.Remove()
 }
+
+func TestAccountList(t *testing.T) {
+	acc := AccountNew("list@example.com")
+	user, err := acc.Store()
+
+	if assert.Nil(t, err) {
+		list, err := AccountList()
+		if assert.Nil(t, err) {
+			assert.True(t, containsAccount(list, user.ID))
+		}
+
+		user.Remove()
+	}
+}
+
+func TestAccountListUnverified(t *testing.T) {
+	acc := AccountNew("unverified@example.com")
+	user, err := acc.Store()
+
+	if assert.Nil(t, err) {
+		list, err := AccountListUnverified(time.Now().Add(time.Hour))
+		if assert.Nil(t, err) {
+			assert.True(t, containsAccount(list, user.ID))
+		}
+
+		list, err = AccountListUnverified(time.Now().Add(-time.Hour))
+		if assert.Nil(t, err) {
+			assert.False(t, containsAccount(list, user.ID))
+		}
+
+		db.Exec("UPDATE account SET created = now() - interval '2 hours' WHERE id = $1", user.ID)
+
+		list, err = AccountListUnverified(time.Now().Add(-time.Hour))
+		if assert.Nil(t, err) {
+			assert.True(t, containsAccount(list, user.ID))
+		}
+
+		_, err = TokenNew(user.ID, TokenTypeMaintenace).Store()
+		assert.Nil(t, err)
+
+		list, err = AccountListUnverified(time.Now().Add(-time.Hour))
+		if assert.Nil(t, err) {
+			assert.False(t, containsAccount(list, user.ID))
+		}
+
+		user, err = user.Verify()
+		assert.Nil(t, err)
+
+		list, err = AccountListUnverified(time.Now().Add(time.Hour))
+		if assert.Nil(t, err) {
+			assert.False(t, containsAccount(list, user.ID))
+		}
+
+		user.Remove()
+	}
+}
+
+func containsAccount(list []*Account, id int) bool {
+	for _, account := range list {
+		if account.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestAccountRemoveKeepsOrganizationNotes(t *testing.T) {
+	author, err := AccountNew("author@example.com").Store()
+	assert.Nil(t, err)
+
+	owner, err := AccountNew("owner@example.com").Store()
+	assert.Nil(t, err)
+
+	org, err := OrganizationNew("Example").Store()
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	_, err = MemberNew(org.ID, owner.ID, RoleOwner).Store()
+	assert.Nil(t, err)
+	_, err = MemberNew(org.ID, author.ID, RoleEditor).Store()
+	assert.Nil(t, err)
+
+	note, err := NoteOrganizationNew(org.ID, author.ID, "shared content").Store()
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	personal, err := NoteNew(author.ID, "personal content").Store()
+	assert.Nil(t, err)
+
+	assert.Nil(t, author.Remove())
+
+	kept, err := note.Refresh()
+	if assert.Nil(t, err) {
+		assert.Equal(t, owner.ID, kept.Account)
+		assert.Equal(t, org.ID, kept.Organization)
+	}
+
+	_, err = personal.Refresh()
+	assert.NotNil(t, err)
+
+	org.Remove()
+	owner.Remove()
+}
+
+func TestAccountDelete(t *testing.T) {
+	owner, err := AccountNew("owner@example.com").Store()
+	assert.Nil(t, err)
+
+	editor, err := AccountNew("editor@example.com").Store()
+	assert.Nil(t, err)
+
+	shared, err := OrganizationNew("Shared").Store()
+	assert.Nil(t, err)
+	sole, err := OrganizationNew("Sole").Store()
+	assert.Nil(t, err)
+
+	_, err = MemberNew(shared.ID, owner.ID, RoleOwner).Store()
+	assert.Nil(t, err)
+	_, err = MemberNew(shared.ID, editor.ID, RoleEditor).Store()
+	assert.Nil(t, err)
+	_, err = MemberNew(sole.ID, owner.ID, RoleOwner).Store()
+	assert.Nil(t, err)
+
+	assert.Equal(t, ErrOrganizationOwner, owner.Delete())
+
+	list, err := editor.GetSoleOrganizations()
+	assert.Nil(t, err)
+	assert.Empty(t, list)
+
+	assert.Nil(t, shared.Remove())
+	assert.Nil(t, owner.Delete())
+
+	_, err = sole.Refresh()
+	assert.NotNil(t, err)
+
+	editor.Remove()
+}
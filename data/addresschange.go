@@ -0,0 +1,154 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"gopkg.in/hlandau/passlib.v1"
+)
+
+// AddressChangeInterface defines AddressChange
+type AddressChangeInterface interface {
+	Confirm() (*Account, error)
+	ConfirmContext(ctx context.Context) (*Account, error)
+	IsStored() bool
+	Matches(raw string) bool
+	Raw() string
+	Store() (*AddressChange, error)
+	StoreContext(ctx context.Context) (*AddressChange, error)
+}
+
+// AddressChange implements AddressChangeInterface and is a requested change
+// of the Account address waiting for confirmation from the new address
+type AddressChange struct {
+	ID      int       `db:"id"`
+	Account int       `db:"account"`
+	Address string    `db:"address"`
+	Text    string    `db:"text"`
+	Created time.Time `db:"created"`
+	raw     string
+}
+
+const addressChangeColumns = "id, account, address, text, created"
+
+// AddressChangeNew creates a new AddressChange of Account to address
+func AddressChangeNew(account int, address string) *AddressChange {
+	token := random(32)
+	hashed, _ := passlib.Hash(token)
+
+	return &AddressChange{0, account, NormalizeAddress(address), hashed, time.Now(), token}
+}
+
+// AddressChangeByID retrieves AddressChange by id
+func AddressChangeByID(id int) (*AddressChange, error) {
+	return AddressChangeByIDContext(context.Background(), id)
+}
+
+// AddressChangeByIDContext is AddressChangeByID bound to ctx
+func AddressChangeByIDContext(ctx context.Context, id int) (*AddressChange, error) {
+	var change AddressChange
+
+	err := with(ctx).get(&change, `SELECT `+addressChangeColumns+`
+		FROM address_change WHERE id = $1`, id)
+
+	return &change, err
+}
+
+// AddressChangeListByAccount retrieves all pending AddressChange of Account
+// created after since
+func AddressChangeListByAccount(account int, since time.Time) []*AddressChange {
+	return AddressChangeListByAccountContext(context.Background(), account, since)
+}
+
+// AddressChangeListByAccountContext is AddressChangeListByAccount bound to
+// ctx
+func AddressChangeListByAccountContext(ctx context.Context, account int, since time.Time) []*AddressChange {
+	var list []*AddressChange
+
+	with(ctx).selectAll(&list, `SELECT `+addressChangeColumns+`
+		FROM address_change WHERE account = $1 AND created > $2
+		ORDER BY id DESC`, account, since)
+
+	return list
+}
+
+// Confirm changes the Account address and removes all pending AddressChange
+// of the Account
+func (c AddressChange) Confirm() (*Account, error) {
+	return c.ConfirmContext(context.Background())
+}
+
+// ConfirmContext is Confirm bound to ctx
+func (c AddressChange) ConfirmContext(ctx context.Context) (*Account, error) {
+	err := TransactionContext(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.Exec("UPDATE account SET address = $2 WHERE id = $1", c.Account, c.Address)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("DELETE FROM address_change WHERE account = $1", c.Account)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return AccountByIDContext(ctx, c.Account)
+}
+
+// IsStored checks if AddressChange is stored in DB
+func (c AddressChange) IsStored() bool {
+	return c.ID != 0
+}
+
+// Matches checks if text matches AddressChange
+func (c AddressChange) Matches(raw string) bool {
+	_, err := passlib.Verify(raw, c.Text)
+
+	return err == nil
+}
+
+// Raw returns AddressChange raw token
+func (c AddressChange) Raw() string {
+	return c.raw
+}
+
+// Store writes AddressChange to DB
+func (c AddressChange) Store() (*AddressChange, error) {
+	return c.StoreContext(context.Background())
+}
+
+// StoreContext is Store bound to ctx
+func (c AddressChange) StoreContext(ctx context.Context) (*AddressChange, error) {
+	var change AddressChange
+	err := with(ctx).get(&change, `
+		insert into address_change (account, address, text)
+		values($1, $2, $3)
+		RETURNING `+addressChangeColumns, c.Account, c.Address, c.Text)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &change, nil
+}
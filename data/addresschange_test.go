@@ -0,0 +1,77 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddressChange(t *testing.T) {
+	acc := AccountNew("mail@example.com")
+	user, err := acc.Store()
+
+	assert.Nil(t, err)
+
+	change := AddressChangeNew(user.ID, "new@example.com")
+
+	assert.NotEqual(t, "", change.Raw())
+	assert.True(t, change.Matches(change.Raw()))
+	assert.False(t, change.Matches("test"+change.Raw()))
+	assert.False(t, change.IsStored())
+
+	change, err = change.Store()
+
+	if assert.Nil(t, err) {
+		assert.True(t, change.IsStored())
+		assert.Equal(t, "", change.Raw())
+
+		list := AddressChangeListByAccount(user.ID, time.Now().Add(-time.Hour))
+		assert.Equal(t, 1, len(list))
+
+		list = AddressChangeListByAccount(user.ID, time.Now().Add(time.Hour))
+		assert.Equal(t, 0, len(list))
+
+		user, err = change.Confirm()
+		if assert.Nil(t, err) {
+			assert.Equal(t, "new@example.com", user.Address)
+		}
+
+		list = AddressChangeListByAccount(user.ID, time.Now().Add(-time.Hour))
+		assert.Equal(t, 0, len(list))
+	}
+
+	// Changing to an address in use fails
+	other, err := AccountNew("other@example.com").Store()
+
+	if assert.Nil(t, err) {
+		change, err = AddressChangeNew(user.ID, other.Address).Store()
+
+		if assert.Nil(t, err) {
+			_, err = change.Confirm()
+			assert.NotNil(t, err)
+		}
+
+		other.Remove()
+	}
+
+	user.Remove()
+}
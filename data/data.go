@@ -19,14 +19,118 @@
 package data
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"math/rand"
+	"reflect"
+	"time"
 
+	"github.com/jackc/pgx"
 	"github.com/jmoiron/sqlx"
 )
 
 var (
 	db      *sqlx.DB
 	letters = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
+
+	// migrations changes the database structure created by Setup; entries
+	// are applied in order and must never be edited once released
+	migrations = []string{
+		`ALTER TABLE subscription ALTER COLUMN stripeid DROP NOT NULL;
+		ALTER TABLE subscription ADD COLUMN trial_end TIMESTAMP;
+		ALTER TABLE subscription ADD COLUMN grace_end TIMESTAMP;
+		ALTER TABLE subscription ADD COLUMN reminded TIMESTAMP;`,
+		`ALTER TABLE account ADD COLUMN customer TEXT;
+		CREATE UNIQUE INDEX account_customer_uindex ON account (customer);`,
+		`CREATE TABLE organization(
+			id serial primary key,
+			name TEXT NOT NULL,
+			created TIMESTAMP DEFAULT now() NOT NULL
+		);
+
+		CREATE TABLE member(
+			id serial primary key,
+			organization INTEGER NOT NULL REFERENCES organization (id) on delete cascade,
+			account INTEGER NOT NULL REFERENCES account (id) on delete cascade,
+			role INTEGER NOT NULL,
+			created TIMESTAMP DEFAULT now() NOT NULL
+		);
+
+		CREATE TABLE invitation(
+			id serial primary key,
+			organization INTEGER NOT NULL REFERENCES organization (id) on delete cascade,
+			address TEXT NOT NULL,
+			role INTEGER NOT NULL,
+			text TEXT NOT NULL,
+			created TIMESTAMP DEFAULT now() NOT NULL
+		);
+
+		CREATE UNIQUE INDEX member_organization_account_uindex ON member (organization, account);
+		ALTER TABLE note ADD COLUMN organization INTEGER REFERENCES organization (id) on delete cascade;`,
+		`CREATE TABLE share(
+			id serial primary key,
+			note INTEGER NOT NULL REFERENCES note (id) on delete cascade,
+			account INTEGER NOT NULL REFERENCES account (id) on delete cascade,
+			text TEXT NOT NULL,
+			created TIMESTAMP DEFAULT now() NOT NULL,
+			expires TIMESTAMP,
+			max_views INTEGER DEFAULT 0 NOT NULL,
+			views INTEGER DEFAULT 0 NOT NULL
+		);
+
+		CREATE UNIQUE INDEX share_text_uindex ON share (text);`,
+		`CREATE TABLE revision(
+			id serial primary key,
+			note INTEGER NOT NULL REFERENCES note (id) on delete cascade,
+			token INTEGER REFERENCES token (id) on delete set null,
+			text TEXT NOT NULL,
+			created TIMESTAMP DEFAULT now() NOT NULL
+		);
+
+		CREATE INDEX revision_note_index ON revision (note);`,
+		`CREATE TABLE address_change(
+			id serial primary key,
+			account INTEGER NOT NULL REFERENCES account (id) on delete cascade,
+			address TEXT NOT NULL,
+			text TEXT NOT NULL,
+			created TIMESTAMP DEFAULT now() NOT NULL
+		);`,
+		`-- Unverified duplicates are dropped
+		DELETE FROM account a USING account b
+		WHERE lower(trim(a.address)) = lower(trim(b.address)) AND a.id <> b.id
+		AND NOT a.verified AND (b.verified OR b.id < a.id);
+
+		-- Verified duplicates keep the address on the oldest account, the
+		-- others are renamed to duplicate-ID+address for manual merging
+		UPDATE account a SET address = 'duplicate-' || a.id || '+' || lower(trim(a.address))
+		FROM account b
+		WHERE lower(trim(a.address)) = lower(trim(b.address)) AND b.id < a.id;
+
+		UPDATE account SET address = lower(trim(address));
+		UPDATE invitation SET address = lower(trim(address));
+		UPDATE address_change SET address = lower(trim(address));
+
+		DROP INDEX account_address_uindex;
+		CREATE UNIQUE INDEX account_address_uindex ON account (lower(address));`,
+		`CREATE TABLE outbox(
+			id serial primary key,
+			address TEXT NOT NULL,
+			template BIGINT NOT NULL,
+			model TEXT NOT NULL,
+			created TIMESTAMP DEFAULT now() NOT NULL,
+			sent TIMESTAMP,
+			attempts INTEGER DEFAULT 0 NOT NULL
+		);
+
+		CREATE INDEX outbox_pending_index ON outbox (id) WHERE sent IS NULL;`,
+		`ALTER TABLE outbox ADD COLUMN next_attempt TIMESTAMP DEFAULT now() NOT NULL;
+		ALTER TABLE outbox ADD COLUMN failed TIMESTAMP;
+		ALTER TABLE outbox ADD COLUMN error TEXT DEFAULT '' NOT NULL;
+
+		DROP INDEX outbox_pending_index;
+		CREATE INDEX outbox_pending_index ON outbox (next_attempt) WHERE sent IS NULL AND failed IS NULL;`,
+	}
 )
 
 // Database configures the db driver
@@ -34,8 +138,13 @@ func Database(use *sqlx.DB) {
 	db = use
 }
 
-// Setup creates the database structure
-func Setup() {
+// Close closes the database pool after running queries have finished
+func Close() error {
+	return db.Close()
+}
+
+// Setup creates the database structure and applies pending migrations
+func Setup() error {
 	db.Exec(`
 		CREATE TABLE account(
 	    id serial primary key,
@@ -81,6 +190,187 @@ func Setup() {
 		ALTER TABLE note ADD FOREIGN KEY (account) REFERENCES account (id) on delete cascade;
 		CREATE UNIQUE INDEX note_id_uindex ON note (id);
 	`)
+
+	return Migrate()
+}
+
+// Migrate applies all pending migrations to the database structure
+func Migrate() error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS migration(
+		version INTEGER PRIMARY KEY,
+		created TIMESTAMP DEFAULT now() NOT NULL
+	)`)
+
+	if err != nil {
+		return err
+	}
+
+	version, err := migrationVersion(with(context.Background()))
+	if err != nil {
+		return err
+	}
+
+	for ; version < len(migrations); version++ {
+		err = Transaction(func(tx *sqlx.Tx) error {
+			if _, err := tx.Exec(migrations[version]); err != nil {
+				return err
+			}
+
+			_, err := tx.Exec("INSERT INTO migration (version) VALUES ($1)", version+1)
+			return err
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MigrationsPending returns the number of migrations not yet applied
+func MigrationsPending() (int, error) {
+	return MigrationsPendingContext(context.Background())
+}
+
+// MigrationsPendingContext is MigrationsPending bound to ctx
+func MigrationsPendingContext(ctx context.Context) (int, error) {
+	version, err := migrationVersion(with(ctx))
+	if err != nil {
+		return 0, err
+	}
+
+	return len(migrations) - version, nil
+}
+
+// IsDuplicate checks if err was caused by a unique index, like the one on
+// addresses of accounts
+func IsDuplicate(err error) bool {
+	var pgErr pgx.PgError
+
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// Ping checks the database connection
+func Ping() error {
+	return PingContext(context.Background())
+}
+
+// PingContext is Ping bound to ctx
+func PingContext(ctx context.Context) error {
+	return db.PingContext(ctx)
+}
+
+func migrationVersion(c conn) (int, error) {
+	var version int
+	err := c.get(&version, "SELECT COALESCE(MAX(version), 0) FROM migration")
+
+	return version, err
+}
+
+// Transaction runs fn in a database transaction which is committed if fn
+// succeeds and rolled back otherwise
+func Transaction(fn func(tx *sqlx.Tx) error) error {
+	return TransactionContext(context.Background(), fn)
+}
+
+// TransactionContext is Transaction rolled back once ctx is done
+func TransactionContext(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	begun, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	tx := &sqlx.Tx{Tx: begun, Mapper: db.Mapper}
+	if err = fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// queryer is implemented by *sqlx.DB and *sqlx.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// conn runs the queries of a data function on the pool or a transaction and
+// cancels them once ctx is done, the vendored sqlx has no context support
+type conn struct {
+	ctx context.Context
+	q   queryer
+}
+
+// with returns a conn using the pool bound to ctx
+func with(ctx context.Context) conn {
+	return conn{ctx, db}
+}
+
+// withTx returns a conn using tx, which is bound to the context it was
+// begun with
+func withTx(tx *sqlx.Tx) conn {
+	return conn{context.Background(), tx}
+}
+
+// get scans the first row of query into dest like sqlx.Get, structs are
+// scanned by column names
+func (c conn) get(dest interface{}, query string, args ...interface{}) error {
+	rows, err := c.q.QueryContext(c.ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		return sql.ErrNoRows
+	}
+
+	if isStruct(dest) {
+		err = (&sqlx.Rows{Rows: rows, Mapper: db.Mapper}).StructScan(dest)
+	} else {
+		err = rows.Scan(dest)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return rows.Close()
+}
+
+// selectAll scans all rows of query into the slice of structs dest like
+// sqlx.Select
+func (c conn) selectAll(dest interface{}, query string, args ...interface{}) error {
+	rows, err := c.q.QueryContext(c.ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	return sqlx.StructScan(&sqlx.Rows{Rows: rows, Mapper: db.Mapper}, dest)
+}
+
+// exec runs query without returning rows
+func (c conn) exec(query string, args ...interface{}) (sql.Result, error) {
+	return c.q.ExecContext(c.ctx, query, args...)
+}
+
+// isStruct checks if dest points to a struct scanned field by field
+func isStruct(dest interface{}) bool {
+	switch dest.(type) {
+	case sql.Scanner, *time.Time:
+		return false
+	}
+
+	return reflect.Indirect(reflect.ValueOf(dest)).Kind() == reflect.Struct
 }
 
 // Get returns `n` random characters
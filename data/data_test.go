@@ -19,13 +19,17 @@
 package data
 
 import (
+	"context"
 	"flag"
 	"os"
+	"sync"
 	"testing"
+	"time"
 
 	_ "github.com/jackc/pgx/stdlib"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestMain(m *testing.M) {
@@ -36,3 +40,83 @@ func TestMain(m *testing.M) {
 	flag.Parse()
 	os.Exit(m.Run())
 }
+
+func TestMigrationsPending(t *testing.T) {
+	assert.Nil(t, Ping())
+
+	pending, err := MigrationsPending()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, pending)
+}
+
+func TestContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := AccountByAddressContext(ctx, "mail@example.com")
+	assert.Equal(t, context.Canceled, err)
+
+	err = TransactionContext(ctx, func(tx *sqlx.Tx) error {
+		return nil
+	})
+	assert.NotNil(t, err)
+}
+
+func TestConnectionsReturned(t *testing.T) {
+	db.SetMaxOpenConns(4)
+	defer db.SetMaxOpenConns(0)
+
+	// a leaked result set holds its connection, the timeout turns the
+	// exhausted pool into a failure instead of a hanging test
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	user, err := AccountNew("stress@example.com").StoreContext(ctx)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			note, err := NoteNew(user.ID, "stress").StoreContext(ctx)
+			if err == nil {
+				note.Text = "stress again"
+				_, err = note.StoreContext(ctx)
+			}
+
+			if err == nil {
+				_, err = user.StoreContext(ctx)
+			}
+
+			errs <- err
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.Nil(t, err)
+	}
+
+	assert.Equal(t, 0, db.Stats().InUse)
+	assert.Nil(t, user.Remove())
+}
+
+func TestIsDuplicate(t *testing.T) {
+	user, err := AccountNew("duplicate@example.com").Store()
+	assert.Nil(t, err)
+
+	_, err = AccountNew("duplicate@example.com").Store()
+	assert.True(t, IsDuplicate(err))
+	assert.False(t, IsDuplicate(nil))
+
+	user.Remove()
+}
@@ -0,0 +1,96 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package data
+
+import "strings"
+
+const (
+	// ChangeEqual marks text present in both versions
+	ChangeEqual = "equal"
+	// ChangeInsert marks text only present in the new version
+	ChangeInsert = "insert"
+	// ChangeDelete marks text only present in the old version
+	ChangeDelete = "delete"
+)
+
+// Change is a part of the difference between two texts
+type Change struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// Diff returns the word based changes needed to turn a into b
+func Diff(a string, b string) []Change {
+	x := strings.Fields(a)
+	y := strings.Fields(b)
+
+	// Length of the longest common subsequence of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var list []Change
+	add := func(kind string, word string) {
+		if n := len(list); n > 0 && list[n-1].Type == kind {
+			list[n-1].Text += " " + word
+			return
+		}
+
+		list = append(list, Change{kind, word})
+	}
+
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			add(ChangeEqual, x[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			add(ChangeDelete, x[i])
+			i++
+		default:
+			add(ChangeInsert, y[j])
+			j++
+		}
+	}
+
+	for ; i < len(x); i++ {
+		add(ChangeDelete, x[i])
+	}
+
+	for ; j < len(y); j++ {
+		add(ChangeInsert, y[j])
+	}
+
+	return list
+}
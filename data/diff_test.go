@@ -0,0 +1,48 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package data
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	assert.Equal(t, 0, len(Diff("", "")))
+
+	assert.Equal(t, []Change{
+		{ChangeInsert, "new note"},
+	}, Diff("", "new note"))
+
+	assert.Equal(t, []Change{
+		{ChangeDelete, "old note"},
+	}, Diff("old note", ""))
+
+	assert.Equal(t, []Change{
+		{ChangeEqual, "buy"},
+		{ChangeDelete, "milk"},
+		{ChangeInsert, "bread and butter"},
+		{ChangeEqual, "today"},
+	}, Diff("buy milk today", "buy bread and butter today"))
+
+	assert.Equal(t, []Change{
+		{ChangeEqual, "same text"},
+	}, Diff("same text", "same  text"))
+}
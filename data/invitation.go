@@ -0,0 +1,173 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package data
+
+import (
+	"context"
+	"time"
+
+	"gopkg.in/hlandau/passlib.v1"
+)
+
+// InvitationInterface defines Invitation
+type InvitationInterface interface {
+	Accept(account int) (*Member, error)
+	AcceptContext(ctx context.Context, account int) (*Member, error)
+	IsStored() bool
+	Matches(raw string) bool
+	Raw() string
+	Remove() error
+	RemoveContext(ctx context.Context) error
+	Store() (*Invitation, error)
+	StoreContext(ctx context.Context) (*Invitation, error)
+}
+
+// Invitation implements InvitationInterface
+type Invitation struct {
+	ID           int       `db:"id"`
+	Organization int       `db:"organization"`
+	Address      string    `db:"address"`
+	Role         int       `db:"role"`
+	Text         string    `db:"text"`
+	Created      time.Time `db:"created"`
+	raw          string
+}
+
+const invitationColumns = "id, organization, address, role, text, created"
+
+// InvitationNew creates a new Invitation to Organization for address
+func InvitationNew(organization int, address string, role int) *Invitation {
+	token := random(32)
+	hashed, _ := passlib.Hash(token)
+
+	return &Invitation{0, organization, NormalizeAddress(address), role, hashed, time.Now(), token}
+}
+
+// InvitationByID retrieves Invitation by id
+func InvitationByID(id int) (*Invitation, error) {
+	return InvitationByIDContext(context.Background(), id)
+}
+
+// InvitationByIDContext is InvitationByID bound to ctx
+func InvitationByIDContext(ctx context.Context, id int) (*Invitation, error) {
+	var inv Invitation
+
+	err := with(ctx).get(&inv, `SELECT `+invitationColumns+`
+		FROM invitation WHERE id = $1`, id)
+
+	return &inv, err
+}
+
+// InvitationListByOrganizationAndAddress retrieves all Invitation to
+// Organization for address
+func InvitationListByOrganizationAndAddress(organization int, address string) []*Invitation {
+	return InvitationListByOrganizationAndAddressContext(context.Background(), organization, address)
+}
+
+// InvitationListByOrganizationAndAddressContext is
+// InvitationListByOrganizationAndAddress bound to ctx
+func InvitationListByOrganizationAndAddressContext(ctx context.Context, organization int, address string) []*Invitation {
+	var list []*Invitation
+
+	with(ctx).selectAll(&list, `SELECT `+invitationColumns+`
+		FROM invitation WHERE organization = $1 AND address = $2`, organization, NormalizeAddress(address))
+
+	return list
+}
+
+// InvitationListByAddress retrieves all Invitation for address
+func InvitationListByAddress(address string) []*Invitation {
+	return InvitationListByAddressContext(context.Background(), address)
+}
+
+// InvitationListByAddressContext is InvitationListByAddress bound to ctx
+func InvitationListByAddressContext(ctx context.Context, address string) []*Invitation {
+	var list []*Invitation
+
+	with(ctx).selectAll(&list, `SELECT `+invitationColumns+`
+		FROM invitation WHERE address = $1 ORDER BY id ASC`, NormalizeAddress(address))
+
+	return list
+}
+
+// Accept adds Account as Member to the Organization and removes Invitation
+func (i Invitation) Accept(account int) (*Member, error) {
+	return i.AcceptContext(context.Background(), account)
+}
+
+// AcceptContext is Accept bound to ctx
+func (i Invitation) AcceptContext(ctx context.Context, account int) (*Member, error) {
+	member := MemberNew(i.Organization, account, i.Role)
+	member, err := member.StoreContext(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return member, i.RemoveContext(ctx)
+}
+
+// IsStored checks if Invitation is stored in DB
+func (i Invitation) IsStored() bool {
+	return i.ID != 0
+}
+
+// Matches checks if text matches Invitation
+func (i Invitation) Matches(raw string) bool {
+	_, err := passlib.Verify(raw, i.Text)
+
+	return err == nil
+}
+
+// Raw returns Invitation raw token
+func (i Invitation) Raw() string {
+	return i.raw
+}
+
+// Remove Invitation
+func (i Invitation) Remove() error {
+	return i.RemoveContext(context.Background())
+}
+
+// RemoveContext is Remove bound to ctx
+func (i Invitation) RemoveContext(ctx context.Context) error {
+	_, err := with(ctx).exec("delete FROM invitation WHERE id = $1", i.ID)
+
+	return err
+}
+
+// Store writes Invitation to DB
+func (i Invitation) Store() (*Invitation, error) {
+	return i.StoreContext(context.Background())
+}
+
+// StoreContext is Store bound to ctx
+func (i Invitation) StoreContext(ctx context.Context) (*Invitation, error) {
+	var inv Invitation
+	err := with(ctx).get(&inv, `
+		insert into invitation (organization, address, role, text)
+		values($1, $2, $3, $4)
+		RETURNING `+invitationColumns, i.Organization, i.Address, i.Role, i.Text)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &inv, nil
+}
@@ -0,0 +1,76 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package data
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvitation(t *testing.T) {
+	acc := AccountNew("mail@example.com")
+	user, err := acc.Store()
+
+	assert.Nil(t, err)
+
+	org, err := OrganizationNew("Example").Store()
+
+	assert.Nil(t, err)
+
+	inv := InvitationNew(org.ID, user.Address, RoleEditor)
+
+	assert.NotEqual(t, "", inv.Raw())
+	assert.True(t, inv.Matches(inv.Raw()))
+	assert.False(t, inv.Matches("test"+inv.Raw()))
+	assert.False(t, inv.IsStored())
+
+	raw := inv.Raw()
+	inv, err = inv.Store()
+
+	if assert.Nil(t, err) {
+		assert.True(t, inv.IsStored())
+		assert.Equal(t, "", inv.Raw())
+
+		list := InvitationListByOrganizationAndAddress(org.ID, user.Address)
+		if assert.Equal(t, 1, len(list)) {
+			assert.True(t, list[0].Matches(raw))
+		}
+
+		member, err := inv.Accept(user.ID)
+		if assert.Nil(t, err) {
+			assert.Equal(t, RoleEditor, member.Role)
+			assert.Equal(t, user.ID, member.Account)
+		}
+
+		assert.Equal(t, 0, len(InvitationListByOrganizationAndAddress(org.ID, user.Address)))
+	}
+
+	// Removing the account removes invitations for its address
+	_, err = InvitationNew(org.ID, user.Address, RoleViewer).Store()
+
+	if assert.Nil(t, err) {
+		assert.Equal(t, 1, len(InvitationListByAddress(user.Address)))
+		assert.Nil(t, user.Remove())
+		assert.Equal(t, 0, len(InvitationListByAddress(user.Address)))
+	}
+
+	org.Remove()
+	user.Remove()
+}
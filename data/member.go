@@ -0,0 +1,189 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package data
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// RoleOwner can manage members and edit notes of an Organization
+	RoleOwner = 1
+	// RoleEditor can read and write notes of an Organization
+	RoleEditor = 2
+	// RoleViewer can read notes of an Organization
+	RoleViewer = 3
+)
+
+// MemberInterface defines Member
+type MemberInterface interface {
+	Can(role int) bool
+	IsStored() bool
+	Remove() error
+	RemoveContext(ctx context.Context) error
+	Store() (*Member, error)
+	StoreContext(ctx context.Context) (*Member, error)
+
+	create(c conn) (*Member, error)
+	update(c conn) (*Member, error)
+}
+
+// Member implements MemberInterface
+type Member struct {
+	ID           int       `db:"id"`
+	Organization int       `db:"organization"`
+	Account      int       `db:"account"`
+	Role         int       `db:"role"`
+	Created      time.Time `db:"created"`
+}
+
+const memberColumns = "id, organization, account, role, created"
+
+// MemberNew creates a new Member
+func MemberNew(organization int, account int, role int) *Member {
+	return &Member{0, organization, account, role, time.Now()}
+}
+
+// MemberByID retrieves Member by id
+func MemberByID(id int) (*Member, error) {
+	return MemberByIDContext(context.Background(), id)
+}
+
+// MemberByIDContext is MemberByID bound to ctx
+func MemberByIDContext(ctx context.Context, id int) (*Member, error) {
+	var member Member
+
+	err := with(ctx).get(&member, `SELECT `+memberColumns+`
+		FROM member WHERE id = $1`, id)
+
+	return &member, err
+}
+
+// MemberByOrganizationAndAccount retrieves Member by Organization and Account
+func MemberByOrganizationAndAccount(organization int, account int) (*Member, error) {
+	return MemberByOrganizationAndAccountContext(context.Background(), organization, account)
+}
+
+// MemberByOrganizationAndAccountContext is MemberByOrganizationAndAccount
+// bound to ctx
+func MemberByOrganizationAndAccountContext(ctx context.Context, organization int, account int) (*Member, error) {
+	var member Member
+
+	err := with(ctx).get(&member, `SELECT `+memberColumns+`
+		FROM member WHERE organization = $1 AND account = $2`, organization, account)
+
+	return &member, err
+}
+
+// MemberListByOrganization retrieves all Member of Organization
+func MemberListByOrganization(organization int) ([]*Member, error) {
+	return MemberListByOrganizationContext(context.Background(), organization)
+}
+
+// MemberListByOrganizationContext is MemberListByOrganization bound to ctx
+func MemberListByOrganizationContext(ctx context.Context, organization int) ([]*Member, error) {
+	var list []*Member
+
+	err := with(ctx).selectAll(&list, `SELECT `+memberColumns+`
+		FROM member WHERE organization = $1 ORDER BY id ASC`, organization)
+
+	return list, err
+}
+
+// MemberListByAccount retrieves all memberships of Account
+func MemberListByAccount(account int) ([]*Member, error) {
+	return MemberListByAccountContext(context.Background(), account)
+}
+
+// MemberListByAccountContext is MemberListByAccount bound to ctx
+func MemberListByAccountContext(ctx context.Context, account int) ([]*Member, error) {
+	var list []*Member
+
+	err := with(ctx).selectAll(&list, `SELECT `+memberColumns+`
+		FROM member WHERE account = $1 ORDER BY id ASC`, account)
+
+	return list, err
+}
+
+// RoleValid checks if role is a known role
+func RoleValid(role int) bool {
+	return role >= RoleOwner && role <= RoleViewer
+}
+
+// Can checks if Member has at least the permissions of role
+func (m Member) Can(role int) bool {
+	return RoleValid(m.Role) && m.Role <= role
+}
+
+// IsStored checks if Member is stored in DB
+func (m Member) IsStored() bool {
+	return m.ID != 0
+}
+
+// Remove Member
+func (m Member) Remove() error {
+	return m.RemoveContext(context.Background())
+}
+
+// RemoveContext is Remove bound to ctx
+func (m Member) RemoveContext(ctx context.Context) error {
+	_, err := with(ctx).exec("delete FROM member WHERE id = $1", m.ID)
+
+	return err
+}
+
+// Store writes Member to DB
+func (m Member) Store() (*Member, error) {
+	return m.StoreContext(context.Background())
+}
+
+// StoreContext is Store bound to ctx
+func (m Member) StoreContext(ctx context.Context) (*Member, error) {
+	if m.IsStored() {
+		return m.update(with(ctx))
+	}
+
+	return m.create(with(ctx))
+}
+
+func (m Member) create(c conn) (*Member, error) {
+	var member Member
+	err := c.get(&member, `
+		insert into member (organization, account, role)
+		values($1, $2, $3)
+		RETURNING `+memberColumns, m.Organization, m.Account, m.Role)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &member, nil
+}
+
+func (m Member) update(c conn) (*Member, error) {
+	var member Member
+	err := c.get(&member, "UPDATE member SET role = $2 WHERE id = $1 RETURNING "+memberColumns, m.ID, m.Role)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &member, nil
+}
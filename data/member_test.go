@@ -0,0 +1,76 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package data
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMember(t *testing.T) {
+	acc := AccountNew("mail@example.com")
+	user, err := acc.Store()
+
+	assert.Nil(t, err)
+
+	org, err := OrganizationNew("Example").Store()
+
+	assert.Nil(t, err)
+
+	member := MemberNew(org.ID, user.ID, RoleViewer)
+
+	assert.False(t, member.IsStored())
+	assert.True(t, member.Can(RoleViewer))
+	assert.False(t, member.Can(RoleEditor))
+	assert.False(t, member.Can(RoleOwner))
+
+	member, err = member.Store()
+
+	if assert.Nil(t, err) {
+		assert.True(t, member.IsStored())
+
+		member.Role = RoleEditor
+		member, err = member.Store()
+		assert.Nil(t, err)
+
+		member2, err2 := org.GetMember(user.ID)
+		if assert.Nil(t, err2) {
+			assert.Equal(t, RoleEditor, member2.Role)
+			assert.True(t, member2.Can(RoleViewer))
+			assert.True(t, member2.Can(RoleEditor))
+			assert.False(t, member2.Can(RoleOwner))
+		}
+
+		duplicate := MemberNew(org.ID, user.ID, RoleOwner)
+		_, err = duplicate.Store()
+		assert.NotNil(t, err)
+
+		assert.Nil(t, member.Remove())
+
+		_, err = org.GetMember(user.ID)
+		assert.NotNil(t, err)
+	}
+
+	assert.False(t, RoleValid(0))
+	assert.False(t, MemberNew(org.ID, user.ID, 4).Can(RoleViewer))
+
+	org.Remove()
+	user.Remove()
+}
@@ -0,0 +1,275 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// MessageInterface defines Message
+type MessageInterface interface {
+	GetModel() map[string]interface{}
+	IsFailed() bool
+	IsSent() bool
+	IsStored() bool
+	MarkDead(reason string) (*Message, error)
+	MarkDeadContext(ctx context.Context, reason string) (*Message, error)
+	MarkFailed(reason string, next time.Time) (*Message, error)
+	MarkFailedContext(ctx context.Context, reason string, next time.Time) (*Message, error)
+	MarkSent() (*Message, error)
+	MarkSentContext(ctx context.Context) (*Message, error)
+	Requeue() (*Message, error)
+	RequeueContext(ctx context.Context) (*Message, error)
+	Store() (*Message, error)
+	StoreContext(ctx context.Context) (*Message, error)
+	StoreTx(tx *sqlx.Tx) (*Message, error)
+}
+
+// Message implements MessageInterface and is a mail waiting in the outbox
+type Message struct {
+	ID       int        `db:"id"`
+	Address  string     `db:"address"`
+	Template int64      `db:"template"`
+	Model    string     `db:"model"`
+	Created  time.Time  `db:"created"`
+	Sent     *time.Time `db:"sent"`
+	Attempts int        `db:"attempts"`
+	Next     time.Time  `db:"next_attempt"`
+	Failed   *time.Time `db:"failed"`
+	Error    string     `db:"error"`
+}
+
+const messageColumns = `id, address, template, model, created, sent, attempts,
+	next_attempt, failed, error`
+
+// MessageNew creates a new Message to address using a mail template
+func MessageNew(address string, template int64, model map[string]interface{}) *Message {
+	encoded, _ := json.Marshal(model)
+	now := time.Now()
+
+	return &Message{0, address, template, string(encoded), now, nil, 0, now, nil, ""}
+}
+
+// MessageByID retrieves Message by id
+func MessageByID(id int) (*Message, error) {
+	return MessageByIDContext(context.Background(), id)
+}
+
+// MessageByIDContext is MessageByID bound to ctx
+func MessageByIDContext(ctx context.Context, id int) (*Message, error) {
+	return messageByID(with(ctx), id)
+}
+
+func messageByID(c conn, id int) (*Message, error) {
+	var message Message
+
+	err := c.get(&message, "SELECT "+messageColumns+" FROM outbox WHERE id = $1", id)
+
+	return &message, err
+}
+
+// MessageClaimPending retrieves up to limit Message due for delivery and
+// postpones them by lease, so concurrent workers do not send them twice
+func MessageClaimPending(limit int, lease time.Duration) ([]*Message, error) {
+	return MessageClaimPendingContext(context.Background(), limit, lease)
+}
+
+// MessageClaimPendingContext is MessageClaimPending bound to ctx
+func MessageClaimPendingContext(ctx context.Context, limit int, lease time.Duration) ([]*Message, error) {
+	var list []*Message
+	now := time.Now()
+
+	err := with(ctx).selectAll(&list, `UPDATE outbox SET next_attempt = $2
+		WHERE id IN (
+			SELECT id FROM outbox
+			WHERE sent IS NULL AND failed IS NULL AND next_attempt <= $1
+			ORDER BY next_attempt ASC LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+messageColumns, now, now.Add(lease), limit)
+
+	return list, err
+}
+
+// MessageListByAddress retrieves all Message to address, oldest first
+func MessageListByAddress(address string) ([]*Message, error) {
+	return MessageListByAddressContext(context.Background(), address)
+}
+
+// MessageListByAddressContext is MessageListByAddress bound to ctx
+func MessageListByAddressContext(ctx context.Context, address string) ([]*Message, error) {
+	var list []*Message
+
+	err := with(ctx).selectAll(&list, `SELECT `+messageColumns+`
+		FROM outbox WHERE lower(address) = $1 ORDER BY id ASC`, NormalizeAddress(address))
+
+	return list, err
+}
+
+// MessageListFailed retrieves up to limit Message which were given up on,
+// latest first
+func MessageListFailed(limit int) ([]*Message, error) {
+	return MessageListFailedContext(context.Background(), limit)
+}
+
+// MessageListFailedContext is MessageListFailed bound to ctx
+func MessageListFailedContext(ctx context.Context, limit int) ([]*Message, error) {
+	var list []*Message
+
+	err := with(ctx).selectAll(&list, `SELECT `+messageColumns+`
+		FROM outbox WHERE failed IS NOT NULL ORDER BY failed DESC LIMIT $1`, limit)
+
+	return list, err
+}
+
+// GetModel returns the template model of Message
+func (m Message) GetModel() map[string]interface{} {
+	model := map[string]interface{}{}
+	json.Unmarshal([]byte(m.Model), &model)
+
+	return model
+}
+
+// IsFailed checks if Message delivery was given up on
+func (m Message) IsFailed() bool {
+	return m.Failed != nil
+}
+
+// IsSent checks if Message has been delivered
+func (m Message) IsSent() bool {
+	return m.Sent != nil
+}
+
+// IsStored checks if Message is stored in DB
+func (m Message) IsStored() bool {
+	return m.ID != 0
+}
+
+// MarkDead records a failed delivery attempt of Message and gives up on it
+func (m Message) MarkDead(reason string) (*Message, error) {
+	return m.MarkDeadContext(context.Background(), reason)
+}
+
+// MarkDeadContext is MarkDead bound to ctx
+func (m Message) MarkDeadContext(ctx context.Context, reason string) (*Message, error) {
+	now := time.Now()
+	m.Attempts++
+	m.Failed = &now
+	m.Error = reason
+
+	return m.StoreContext(ctx)
+}
+
+// MarkFailed records a failed delivery attempt of Message and retries it
+// at next
+func (m Message) MarkFailed(reason string, next time.Time) (*Message, error) {
+	return m.MarkFailedContext(context.Background(), reason, next)
+}
+
+// MarkFailedContext is MarkFailed bound to ctx
+func (m Message) MarkFailedContext(ctx context.Context, reason string, next time.Time) (*Message, error) {
+	m.Attempts++
+	m.Next = next
+	m.Error = reason
+
+	return m.StoreContext(ctx)
+}
+
+// MarkSent marks Message as delivered and drops its model, as it may
+// contain tokens which must not be kept around
+func (m Message) MarkSent() (*Message, error) {
+	return m.MarkSentContext(context.Background())
+}
+
+// MarkSentContext is MarkSent bound to ctx
+func (m Message) MarkSentContext(ctx context.Context) (*Message, error) {
+	now := time.Now()
+	m.Sent = &now
+	m.Model = "{}"
+
+	return m.StoreContext(ctx)
+}
+
+// Requeue schedules a failed Message for immediate delivery
+func (m Message) Requeue() (*Message, error) {
+	return m.RequeueContext(context.Background())
+}
+
+// RequeueContext is Requeue bound to ctx
+func (m Message) RequeueContext(ctx context.Context) (*Message, error) {
+	m.Attempts = 0
+	m.Next = time.Now()
+	m.Failed = nil
+
+	return m.StoreContext(ctx)
+}
+
+// Store writes Message to DB
+func (m Message) Store() (*Message, error) {
+	return m.StoreContext(context.Background())
+}
+
+// StoreContext is Store bound to ctx
+func (m Message) StoreContext(ctx context.Context) (*Message, error) {
+	return m.store(with(ctx))
+}
+
+// StoreTx writes Message to DB as part of transaction tx
+func (m Message) StoreTx(tx *sqlx.Tx) (*Message, error) {
+	return m.store(withTx(tx))
+}
+
+func (m Message) store(c conn) (*Message, error) {
+	if m.IsStored() {
+		return m.update(c)
+	}
+
+	return m.create(c)
+}
+
+func (m Message) create(c conn) (*Message, error) {
+	var message Message
+	err := c.get(&message, `
+		insert into outbox (address, template, model, next_attempt)
+		values($1, $2, $3, $4)
+		RETURNING `+messageColumns, m.Address, m.Template, m.Model, m.Next)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &message, nil
+}
+
+func (m Message) update(c conn) (*Message, error) {
+	var message Message
+	err := c.get(&message, `UPDATE outbox SET model = $2, sent = $3, attempts = $4,
+		next_attempt = $5, failed = $6, error = $7
+		WHERE id = $1 RETURNING `+messageColumns, m.ID, m.Model, m.Sent, m.Attempts, m.Next, m.Failed, m.Error)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &message, nil
+}
@@ -0,0 +1,137 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package data
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessage(t *testing.T) {
+	message := MessageNew("mail@example.com", 42, map[string]interface{}{
+		"token": "secret",
+	})
+
+	assert.False(t, message.IsStored())
+	assert.Equal(t, "secret", message.GetModel()["token"])
+
+	message, err := message.Store()
+
+	if assert.Nil(t, err) {
+		assert.True(t, message.IsStored())
+		assert.False(t, message.IsSent())
+		assert.False(t, message.IsFailed())
+		assert.Equal(t, int64(42), message.Template)
+
+		list, err := MessageListByAddress("Mail@Example.com")
+		if assert.Nil(t, err) {
+			assert.True(t, containsMessage(list, message.ID))
+		}
+
+		// Claimed messages are not handed out again until the lease ends
+		list, err = MessageClaimPending(1000, time.Hour)
+		if assert.Nil(t, err) {
+			assert.True(t, containsMessage(list, message.ID))
+		}
+
+		list, err = MessageClaimPending(1000, time.Hour)
+		if assert.Nil(t, err) {
+			assert.False(t, containsMessage(list, message.ID))
+		}
+
+		message, err = message.MarkFailed("timeout", time.Now().Add(-time.Second))
+		if assert.Nil(t, err) {
+			assert.Equal(t, 1, message.Attempts)
+			assert.Equal(t, "timeout", message.Error)
+			assert.Equal(t, "secret", message.GetModel()["token"])
+		}
+
+		message, err = message.MarkDead("rejected")
+		if assert.Nil(t, err) {
+			assert.Equal(t, 2, message.Attempts)
+			assert.True(t, message.IsFailed())
+		}
+
+		list, err = MessageClaimPending(1000, time.Hour)
+		if assert.Nil(t, err) {
+			assert.False(t, containsMessage(list, message.ID))
+		}
+
+		list, err = MessageListFailed(1000)
+		if assert.Nil(t, err) {
+			assert.True(t, containsMessage(list, message.ID))
+		}
+
+		message, err = message.Requeue()
+		if assert.Nil(t, err) {
+			assert.False(t, message.IsFailed())
+			assert.Equal(t, 0, message.Attempts)
+		}
+
+		message, err = message.MarkSent()
+		if assert.Nil(t, err) {
+			assert.True(t, message.IsSent())
+			assert.Equal(t, 0, len(message.GetModel()))
+		}
+
+		db.Exec("DELETE FROM outbox WHERE id = $1", message.ID)
+	}
+}
+
+func TestMessageTransaction(t *testing.T) {
+	var stored *Message
+
+	err := Transaction(func(tx *sqlx.Tx) error {
+		account, err := AccountNew("rollback@example.com").StoreTx(tx)
+		if err != nil {
+			return err
+		}
+
+		stored, err = MessageNew(account.Address, 42, nil).StoreTx(tx)
+		if err != nil {
+			return err
+		}
+
+		return errors.New("rollback")
+	})
+
+	assert.NotNil(t, err)
+
+	if assert.NotNil(t, stored) {
+		_, err = MessageByID(stored.ID)
+		assert.NotNil(t, err)
+	}
+
+	_, err = AccountByAddress("rollback@example.com")
+	assert.NotNil(t, err)
+}
+
+func containsMessage(list []*Message, id int) bool {
+	for _, message := range list {
+		if message.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
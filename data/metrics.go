@@ -0,0 +1,58 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package data
+
+import (
+	"database/sql"
+
+	"github.com/clinotes/server/metrics"
+)
+
+var metricNotesCreated = metrics.NewCounter("clinotes_notes_created_total", "Notes created.")
+
+func init() {
+	metrics.NewGauge("clinotes_db_connections_open", "Open database connections.", func() float64 {
+		return float64(stats().OpenConnections)
+	})
+	metrics.NewGauge("clinotes_db_connections_in_use", "Database connections in use.", func() float64 {
+		return float64(stats().InUse)
+	})
+	metrics.NewGauge("clinotes_db_connections_idle", "Idle database connections.", func() float64 {
+		return float64(stats().Idle)
+	})
+	metrics.NewGauge("clinotes_db_connections_max", "Maximum open database connections, 0 is unlimited.", func() float64 {
+		return float64(stats().MaxOpenConnections)
+	})
+	metrics.NewCounterFunc("clinotes_db_wait_count_total", "Times a query waited for a free connection.", func() float64 {
+		return float64(stats().WaitCount)
+	})
+	metrics.NewCounterFunc("clinotes_db_wait_seconds_total", "Time spent waiting for a free connection.", func() float64 {
+		return stats().WaitDuration.Seconds()
+	})
+}
+
+// stats returns the statistics of the database pool, which are empty
+// before Database is called
+func stats() sql.DBStats {
+	if db == nil {
+		return sql.DBStats{}
+	}
+
+	return db.Stats()
+}
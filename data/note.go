@@ -19,53 +19,283 @@
 package data
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"time"
+
+	"github.com/jmoiron/sqlx"
 )
 
 // NoteInterface defines Note
 type NoteInterface interface {
+	GetRevisionList() ([]*Revision, error)
+	GetRevisionListContext(ctx context.Context) ([]*Revision, error)
 	IsStored() bool
+	Remove() error
+	RemoveContext(ctx context.Context) error
 	Store() (*Note, error)
+	StoreContext(ctx context.Context) (*Note, error)
+	StoreWithToken(token int) (*Note, error)
+	StoreWithTokenContext(ctx context.Context, token int) (*Note, error)
 
-	create() (*Note, error)
-	update() (*Note, error)
+	create(c conn) (*Note, error)
+	update(c conn) (*Note, error)
 }
 
 // Note implements NoteInterface
 type Note struct {
-	ID      int       `db:"id"`
-	Account int       `db:"account"`
-	Text    string    `db:"text"`
-	Created time.Time `db:"created"`
+	ID           int       `db:"id"`
+	Account      int       `db:"account"`
+	Text         string    `db:"text"`
+	Created      time.Time `db:"created"`
+	Organization int       `db:"organization"`
 }
 
+const noteColumns = "id, account, text, created, COALESCE(organization, 0) AS organization"
+
 // NoteNew creates a new Note
 func NoteNew(account int, text string) *Note {
-	return &Note{0, account, text, time.Now()}
+	return &Note{0, account, text, time.Now(), 0}
+}
+
+// NoteOrganizationNew creates a new Note owned by Organization
+func NoteOrganizationNew(organization int, account int, text string) *Note {
+	return &Note{0, account, text, time.Now(), organization}
 }
 
 // NoteByID retrieves Note by id
 func NoteByID(id int) (*Note, error) {
+	return NoteByIDContext(context.Background(), id)
+}
+
+// NoteByIDContext is NoteByID bound to ctx
+func NoteByIDContext(ctx context.Context, id int) (*Note, error) {
 	var note Note
 
-	err := db.Get(&note, "SELECT id, account, text, created FROM note WHERE id = $1", id)
+	err := with(ctx).get(&note, "SELECT "+noteColumns+" FROM note WHERE id = $1", id)
 
 	return &note, err
 }
 
-// NoteListByAccount retrieves Note by id
+// NoteListByAccount retrieves the latest personal Note of Account
 func NoteListByAccount(account int) ([]Note, error) {
+	return NoteListByAccountContext(context.Background(), account)
+}
+
+// NoteListByAccountContext is NoteListByAccount bound to ctx
+func NoteListByAccountContext(ctx context.Context, account int) ([]Note, error) {
 	var list []Note
 
-	err := db.Select(&list, `SELECT * FROM (
-		SELECT id, account, text, created FROM note
-		WHERE account = $1 ORDER BY id DESC LIMIT 10
+	err := with(ctx).selectAll(&list, `SELECT * FROM (
+		SELECT `+noteColumns+` FROM note
+		WHERE account = $1 AND organization IS NULL ORDER BY id DESC LIMIT 10
 	) as list ORDER BY id ASC`, account)
 
 	return list, err
 }
 
+// NoteListByOrganization retrieves the latest Note of Organization
+func NoteListByOrganization(organization int) ([]Note, error) {
+	return NoteListByOrganizationContext(context.Background(), organization)
+}
+
+// NoteListByOrganizationContext is NoteListByOrganization bound to ctx
+func NoteListByOrganizationContext(ctx context.Context, organization int) ([]Note, error) {
+	var list []Note
+
+	err := with(ctx).selectAll(&list, `SELECT * FROM (
+		SELECT `+noteColumns+` FROM note
+		WHERE organization = $1 ORDER BY id DESC LIMIT 10
+	) as list ORDER BY id ASC`, organization)
+
+	return list, err
+}
+
+// NoteEachByAccount calls fn for every personal Note of Account, oldest
+// first, without loading all of them into memory
+func NoteEachByAccount(account int, fn func(*Note) error) error {
+	return NoteEachByAccountContext(context.Background(), account, fn)
+}
+
+// NoteEachByAccountContext is NoteEachByAccount bound to ctx
+func NoteEachByAccountContext(ctx context.Context, account int, fn func(*Note) error) error {
+	return noteEach(with(ctx), fn, `SELECT `+noteColumns+` FROM note
+		WHERE account = $1 AND organization IS NULL ORDER BY created ASC, id ASC`, account)
+}
+
+// NoteEachByOrganization calls fn for every Note of Organization, oldest
+// first, without loading all of them into memory
+func NoteEachByOrganization(organization int, fn func(*Note) error) error {
+	return NoteEachByOrganizationContext(context.Background(), organization, fn)
+}
+
+// NoteEachByOrganizationContext is NoteEachByOrganization bound to ctx
+func NoteEachByOrganizationContext(ctx context.Context, organization int, fn func(*Note) error) error {
+	return noteEach(with(ctx), fn, `SELECT `+noteColumns+` FROM note
+		WHERE organization = $1 ORDER BY created ASC, id ASC`, organization)
+}
+
+// NoteEachByAuthor calls fn for every Note written by Account with its
+// Revision list, including notes of organizations, oldest first. Revisions
+// are joined in, fn must not query the database while the rows are open
+func NoteEachByAuthor(account int, fn func(*Note, []*Revision) error) error {
+	return NoteEachByAuthorContext(context.Background(), account, fn)
+}
+
+// NoteEachByAuthorContext is NoteEachByAuthor bound to ctx
+func NoteEachByAuthorContext(ctx context.Context, account int, fn func(*Note, []*Revision) error) error {
+	rows, err := db.QueryContext(ctx, `SELECT note.id, note.account, note.text, note.created,
+		COALESCE(note.organization, 0), revision.id, COALESCE(revision.token, 0),
+		revision.text, revision.created
+		FROM note LEFT JOIN revision ON revision.note = note.id
+		WHERE note.account = $1
+		ORDER BY note.created ASC, note.id ASC, revision.id ASC`, account)
+
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	var note *Note
+	var revisions []*Revision
+
+	for rows.Next() {
+		var current Note
+		var id sql.NullInt64
+		var token int
+		var text sql.NullString
+		var created sql.NullTime
+
+		err = rows.Scan(&current.ID, &current.Account, &current.Text, &current.Created,
+			&current.Organization, &id, &token, &text, &created)
+
+		if err != nil {
+			return err
+		}
+
+		if note == nil || note.ID != current.ID {
+			if note != nil {
+				if err = fn(note, revisions); err != nil {
+					return err
+				}
+			}
+
+			note, revisions = &current, nil
+		}
+
+		if id.Valid {
+			revisions = append(revisions, &Revision{int(id.Int64), current.ID, token, text.String, created.Time})
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	if note == nil {
+		return nil
+	}
+
+	return fn(note, revisions)
+}
+
+func noteEach(c conn, fn func(*Note) error, query string, args ...interface{}) error {
+	result, err := c.q.QueryContext(c.ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rows := &sqlx.Rows{Rows: result, Mapper: db.Mapper}
+	defer rows.Close()
+
+	for rows.Next() {
+		var note Note
+
+		if err = rows.StructScan(&note); err != nil {
+			return err
+		}
+
+		if err = fn(&note); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// NoteImport writes all Note in a single transaction keeping their created
+// date and records a Revision made using Token for each of them. Note
+// already stored with the same text and date are skipped, the result tells
+// which Note have been written.
+func NoteImport(list []*Note, token int) ([]bool, error) {
+	return NoteImportContext(context.Background(), list, token)
+}
+
+// NoteImportContext is NoteImport bound to ctx
+func NoteImportContext(ctx context.Context, list []*Note, token int) ([]bool, error) {
+	for _, n := range list {
+		if err := n.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	written := make([]bool, len(list))
+	err := TransactionContext(ctx, func(tx *sqlx.Tx) error {
+		c := withTx(tx)
+
+		for i, n := range list {
+			var id int
+			err := c.get(&id, `INSERT INTO note (account, text, created, organization)
+				SELECT $1, $2, $3, NULLIF($4, 0)
+				WHERE NOT EXISTS (
+					SELECT 1 FROM note WHERE account = $1 AND text = $2 AND created = $3
+					AND COALESCE(organization, 0) = $4
+				) RETURNING id`, n.Account, n.Text, n.Created, n.Organization)
+
+			if err == sql.ErrNoRows {
+				continue
+			}
+
+			if err == nil {
+				_, err = c.exec(`insert into revision (note, token, text, created)
+					values($1, NULLIF($2, 0), $3, $4)`, id, token, n.Text, n.Created)
+			}
+
+			if err != nil {
+				return err
+			}
+
+			written[i] = true
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ok := range written {
+		if ok {
+			metricNotesCreated.Inc()
+		}
+	}
+
+	return written, nil
+}
+
+// GetRevisionList retrieves all Revision of Note
+func (n Note) GetRevisionList() ([]*Revision, error) {
+	return n.GetRevisionListContext(context.Background())
+}
+
+// GetRevisionListContext is GetRevisionList bound to ctx
+func (n Note) GetRevisionListContext(ctx context.Context) ([]*Revision, error) {
+	return RevisionListByNoteContext(ctx, n.ID)
+}
+
 // IsStored checks if Note is stored in DB
 func (n Note) IsStored() bool {
 	return n.ID != 0
@@ -73,42 +303,107 @@ func (n Note) IsStored() bool {
 
 // Refresh Note from DB
 func (n Note) Refresh() (*Note, error) {
-	return NoteByID(n.ID)
+	return n.RefreshContext(context.Background())
+}
+
+// RefreshContext is Refresh bound to ctx
+func (n Note) RefreshContext(ctx context.Context) (*Note, error) {
+	return NoteByIDContext(ctx, n.ID)
+}
+
+// Remove Note and its Revision list
+func (n Note) Remove() error {
+	return n.RemoveContext(context.Background())
+}
+
+// RemoveContext is Remove bound to ctx
+func (n Note) RemoveContext(ctx context.Context) error {
+	_, err := with(ctx).exec("DELETE FROM note WHERE id = $1", n.ID)
+
+	return err
 }
 
 // Store writes Notes to DB
 func (n Note) Store() (*Note, error) {
-	if len(n.Text) > 100 {
-		return nil, errors.New("Note must not be longer than 100 characters")
+	return n.StoreContext(context.Background())
+}
+
+// StoreContext is Store bound to ctx
+func (n Note) StoreContext(ctx context.Context) (*Note, error) {
+	return n.StoreWithTokenContext(ctx, 0)
+}
+
+// StoreWithToken writes Note to DB and records the change as Revision made
+// using Token
+func (n Note) StoreWithToken(token int) (*Note, error) {
+	return n.StoreWithTokenContext(context.Background(), token)
+}
+
+// StoreWithTokenContext is StoreWithToken bound to ctx
+func (n Note) StoreWithTokenContext(ctx context.Context, token int) (*Note, error) {
+	if err := n.Validate(); err != nil {
+		return nil, err
 	}
 
-	if n.IsStored() {
-		return n.update()
+	// Note and its Revision are written together or not at all
+	var note *Note
+	err := TransactionContext(ctx, func(tx *sqlx.Tx) error {
+		c := withTx(tx)
+
+		var err error
+		if n.IsStored() {
+			note, err = n.update(c)
+		} else {
+			note, err = n.create(c)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		_, err = RevisionNew(note.ID, token, note.Text).create(c)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
 	}
 
-	return n.create()
+	if !n.IsStored() {
+		metricNotesCreated.Inc()
+	}
+
+	return note, nil
 }
 
-func (n Note) create() (*Note, error) {
-	var id int
-	rows, err := db.Query("insert into note (account, text) values($1, $2) RETURNING id", n.Account, n.Text)
+// Validate checks if Note can be stored
+func (n Note) Validate() error {
+	if len(n.Text) > 100 {
+		return errors.New("Note must not be longer than 100 characters")
+	}
+
+	return nil
+}
+
+func (n Note) create(c conn) (*Note, error) {
+	var note Note
+	err := c.get(&note, `insert into note (account, text, organization)
+		values($1, $2, NULLIF($3, 0)) RETURNING `+noteColumns, n.Account, n.Text, n.Organization)
 
 	if err != nil {
 		return nil, err
 	}
 
-	rows.Next()
-	rows.Scan(&id)
-
-	return NoteByID(id)
+	return &note, nil
 }
 
-func (n Note) update() (*Note, error) {
-	_, err := db.Query("UPDATE note SET text = $2 WHERE id = $1", n.Account, n.Text)
+func (n Note) update(c conn) (*Note, error) {
+	var note Note
+	err := c.get(&note, `UPDATE note SET text = $2 WHERE id = $1 RETURNING `+noteColumns, n.ID, n.Text)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return &n, nil
+	return &note, nil
 }
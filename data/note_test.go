@@ -19,7 +19,9 @@
 package data
 
 import (
+	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -40,6 +42,14 @@ func TestNote(t *testing.T) {
 
 	if assert.Nil(t, err) {
 		assert.True(t, note.IsStored())
+
+		assert.Nil(t, note.Remove())
+
+		_, err = note.Refresh()
+		assert.NotNil(t, err)
+
+		_, err = note.Store()
+		assert.Equal(t, sql.ErrNoRows, err)
 	}
 
 	user.Remove()
@@ -84,5 +94,113 @@ func TestNoteList(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, 2, len(list))
 
+	var texts []string
+	err = NoteEachByAccount(user.ID, func(n *Note) error {
+		texts = append(texts, n.Text)
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"This is a note!", "This is a second note!"}, texts)
+
+	note.Text = "This is a changed note!"
+	_, err = note.Store()
+	assert.Nil(t, err)
+
+	revisions := map[int]int{}
+	err = NoteEachByAuthor(user.ID, func(n *Note, list []*Revision) error {
+		revisions[n.ID] = len(list)
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[int]int{note.ID: 2, note2.ID: 1}, revisions)
+
+	user.Remove()
+}
+
+func TestNoteOrganization(t *testing.T) {
+	acc := AccountNew("mail@example.com")
+	user, err := acc.Store()
+
+	assert.Nil(t, err)
+
+	org, err := OrganizationNew("Example").Store()
+
+	assert.Nil(t, err)
+
+	note := NoteOrganizationNew(org.ID, user.ID, "Shared note")
+	note, err = note.Store()
+
+	if assert.Nil(t, err) {
+		assert.Equal(t, org.ID, note.Organization)
+	}
+
+	personal := NoteNew(user.ID, "Personal note")
+	personal, err = personal.Store()
+
+	if assert.Nil(t, err) {
+		assert.Equal(t, 0, personal.Organization)
+	}
+
+	list, err := NoteListByOrganization(org.ID)
+
+	if assert.Nil(t, err) && assert.Equal(t, 1, len(list)) {
+		assert.Equal(t, "Shared note", list[0].Text)
+	}
+
+	list, err = NoteListByAccount(user.ID)
+
+	if assert.Nil(t, err) && assert.Equal(t, 1, len(list)) {
+		assert.Equal(t, "Personal note", list[0].Text)
+	}
+
+	org.Remove()
+	user.Remove()
+}
+
+func TestNoteImport(t *testing.T) {
+	acc := AccountNew("mail@example.com")
+	user, err := acc.Store()
+
+	assert.Nil(t, err)
+
+	created := time.Date(2016, 10, 1, 12, 0, 0, 0, time.UTC)
+	first := NoteNew(user.ID, "Imported note")
+	first.Created = created
+	second := NoteNew(user.ID, "Another imported note")
+	second.Created = created.Add(time.Hour)
+
+	written, err := NoteImport([]*Note{first, second}, 0)
+
+	if assert.Nil(t, err) {
+		assert.Equal(t, []bool{true, true}, written)
+	}
+
+	// Importing the same notes again skips them
+	written, err = NoteImport([]*Note{first, second}, 0)
+
+	if assert.Nil(t, err) {
+		assert.Equal(t, []bool{false, false}, written)
+	}
+
+	list, err := NoteListByAccount(user.ID)
+
+	if assert.Nil(t, err) && assert.Equal(t, 2, len(list)) {
+		assert.True(t, created.Equal(list[0].Created))
+	}
+
+	// Invalid notes fail the whole import
+	invalid := NoteNew(user.ID, "This is a note! This is a note! This is a note! This is a note! This is a note! This is a note! This is a note!")
+	third := NoteNew(user.ID, "Not imported")
+
+	_, err = NoteImport([]*Note{third, invalid}, 0)
+	assert.NotNil(t, err)
+
+	list, err = NoteListByAccount(user.ID)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(list))
+
 	user.Remove()
 }
@@ -0,0 +1,169 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package data
+
+import (
+	"context"
+	"time"
+)
+
+// OrganizationInterface defines Organization
+type OrganizationInterface interface {
+	GetMember(account int) (*Member, error)
+	GetMemberContext(ctx context.Context, account int) (*Member, error)
+	GetMemberList() ([]*Member, error)
+	GetMemberListContext(ctx context.Context) ([]*Member, error)
+	IsStored() bool
+	Refresh() (*Organization, error)
+	RefreshContext(ctx context.Context) (*Organization, error)
+	Remove() error
+	RemoveContext(ctx context.Context) error
+	Store() (*Organization, error)
+	StoreContext(ctx context.Context) (*Organization, error)
+
+	create(c conn) (*Organization, error)
+	update(c conn) (*Organization, error)
+}
+
+// Organization implements OrganizationInterface
+type Organization struct {
+	ID      int       `db:"id"`
+	Name    string    `db:"name"`
+	Created time.Time `db:"created"`
+}
+
+const organizationColumns = "id, name, created"
+
+// OrganizationNew creates a new Organization
+func OrganizationNew(name string) *Organization {
+	return &Organization{0, name, time.Now()}
+}
+
+// OrganizationByID retrieves Organization by id
+func OrganizationByID(id int) (*Organization, error) {
+	return OrganizationByIDContext(context.Background(), id)
+}
+
+// OrganizationByIDContext is OrganizationByID bound to ctx
+func OrganizationByIDContext(ctx context.Context, id int) (*Organization, error) {
+	var org Organization
+
+	err := with(ctx).get(&org, "SELECT "+organizationColumns+" FROM organization WHERE id = $1", id)
+
+	return &org, err
+}
+
+// OrganizationListByAccount retrieves all Organization the Account is member of
+func OrganizationListByAccount(account int) ([]*Organization, error) {
+	return OrganizationListByAccountContext(context.Background(), account)
+}
+
+// OrganizationListByAccountContext is OrganizationListByAccount bound to ctx
+func OrganizationListByAccountContext(ctx context.Context, account int) ([]*Organization, error) {
+	var list []*Organization
+
+	err := with(ctx).selectAll(&list, `SELECT o.id, o.name, o.created
+		FROM organization o JOIN member m ON m.organization = o.id
+		WHERE m.account = $1 ORDER BY o.id ASC`, account)
+
+	return list, err
+}
+
+// GetMember retrieves Member of Organization by Account id
+func (o Organization) GetMember(account int) (*Member, error) {
+	return o.GetMemberContext(context.Background(), account)
+}
+
+// GetMemberContext is GetMember bound to ctx
+func (o Organization) GetMemberContext(ctx context.Context, account int) (*Member, error) {
+	return MemberByOrganizationAndAccountContext(ctx, o.ID, account)
+}
+
+// GetMemberList retrieves all Member of Organization
+func (o Organization) GetMemberList() ([]*Member, error) {
+	return o.GetMemberListContext(context.Background())
+}
+
+// GetMemberListContext is GetMemberList bound to ctx
+func (o Organization) GetMemberListContext(ctx context.Context) ([]*Member, error) {
+	return MemberListByOrganizationContext(ctx, o.ID)
+}
+
+// IsStored checks if Organization is stored in DB
+func (o Organization) IsStored() bool {
+	return o.ID != 0
+}
+
+// Refresh Organization from DB
+func (o Organization) Refresh() (*Organization, error) {
+	return o.RefreshContext(context.Background())
+}
+
+// RefreshContext is Refresh bound to ctx
+func (o Organization) RefreshContext(ctx context.Context) (*Organization, error) {
+	return OrganizationByIDContext(ctx, o.ID)
+}
+
+// Remove Organization
+func (o Organization) Remove() error {
+	return o.RemoveContext(context.Background())
+}
+
+// RemoveContext is Remove bound to ctx
+func (o Organization) RemoveContext(ctx context.Context) error {
+	_, err := with(ctx).exec("delete FROM organization WHERE id = $1", o.ID)
+
+	return err
+}
+
+// Store writes Organization to DB
+func (o Organization) Store() (*Organization, error) {
+	return o.StoreContext(context.Background())
+}
+
+// StoreContext is Store bound to ctx
+func (o Organization) StoreContext(ctx context.Context) (*Organization, error) {
+	if o.IsStored() {
+		return o.update(with(ctx))
+	}
+
+	return o.create(with(ctx))
+}
+
+func (o Organization) create(c conn) (*Organization, error) {
+	var org Organization
+	err := c.get(&org, "insert into organization (name) values($1) RETURNING "+organizationColumns, o.Name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &org, nil
+}
+
+func (o Organization) update(c conn) (*Organization, error) {
+	var org Organization
+	err := c.get(&org, "UPDATE organization SET name = $2 WHERE id = $1 RETURNING "+organizationColumns, o.ID, o.Name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &org, nil
+}
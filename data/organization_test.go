@@ -0,0 +1,71 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package data
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrganization(t *testing.T) {
+	acc := AccountNew("mail@example.com")
+	user, err := acc.Store()
+
+	assert.Nil(t, err)
+
+	org := OrganizationNew("Example")
+
+	assert.Equal(t, "Example", org.Name)
+	assert.False(t, org.IsStored())
+
+	org, err = org.Store()
+
+	if assert.Nil(t, err) {
+		assert.True(t, org.IsStored())
+
+		org.Name = "Example Inc."
+		org, err = org.Store()
+		assert.Nil(t, err)
+
+		org2, err2 := org.Refresh()
+		if assert.Nil(t, err2) {
+			assert.Equal(t, "Example Inc.", org2.Name)
+		}
+
+		member := MemberNew(org.ID, user.ID, RoleOwner)
+		_, err = member.Store()
+		assert.Nil(t, err)
+
+		list, err := OrganizationListByAccount(user.ID)
+		if assert.Nil(t, err) {
+			assert.Equal(t, 1, len(list))
+			assert.Equal(t, org.ID, list[0].ID)
+		}
+
+		members, err := org.GetMemberList()
+		if assert.Nil(t, err) {
+			assert.Equal(t, 1, len(members))
+		}
+
+		org.Remove()
+	}
+
+	user.Remove()
+}
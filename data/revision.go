@@ -0,0 +1,123 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// RevisionInterface defines Revision
+type RevisionInterface interface {
+	Diff(previous *Revision) []Change
+	IsStored() bool
+	Store() (*Revision, error)
+	StoreContext(ctx context.Context) (*Revision, error)
+
+	create(c conn) (*Revision, error)
+}
+
+// Revision implements RevisionInterface and is an immutable version of Note
+type Revision struct {
+	ID      int       `db:"id"`
+	Note    int       `db:"note"`
+	Token   int       `db:"token"`
+	Text    string    `db:"text"`
+	Created time.Time `db:"created"`
+}
+
+const revisionColumns = "id, note, COALESCE(token, 0) AS token, text, created"
+
+// RevisionNew creates a new Revision of Note made using Token
+func RevisionNew(note int, token int, text string) *Revision {
+	return &Revision{0, note, token, text, time.Now()}
+}
+
+// RevisionByID retrieves Revision by id
+func RevisionByID(id int) (*Revision, error) {
+	return RevisionByIDContext(context.Background(), id)
+}
+
+// RevisionByIDContext is RevisionByID bound to ctx
+func RevisionByIDContext(ctx context.Context, id int) (*Revision, error) {
+	var revision Revision
+
+	err := with(ctx).get(&revision, `SELECT `+revisionColumns+`
+		FROM revision WHERE id = $1`, id)
+
+	return &revision, err
+}
+
+// RevisionListByNote retrieves all Revision of Note, oldest first
+func RevisionListByNote(note int) ([]*Revision, error) {
+	return RevisionListByNoteContext(context.Background(), note)
+}
+
+// RevisionListByNoteContext is RevisionListByNote bound to ctx
+func RevisionListByNoteContext(ctx context.Context, note int) ([]*Revision, error) {
+	var list []*Revision
+
+	err := with(ctx).selectAll(&list, `SELECT `+revisionColumns+`
+		FROM revision WHERE note = $1 ORDER BY id ASC`, note)
+
+	return list, err
+}
+
+// Diff returns the changes from previous to Revision, previous may be nil
+func (r Revision) Diff(previous *Revision) []Change {
+	if previous == nil {
+		return Diff("", r.Text)
+	}
+
+	return Diff(previous.Text, r.Text)
+}
+
+// IsStored checks if Revision is stored in DB
+func (r Revision) IsStored() bool {
+	return r.ID != 0
+}
+
+// Store writes Revision to DB
+func (r Revision) Store() (*Revision, error) {
+	return r.StoreContext(context.Background())
+}
+
+// StoreContext is Store bound to ctx
+func (r Revision) StoreContext(ctx context.Context) (*Revision, error) {
+	if r.IsStored() {
+		return nil, errors.New("Revision must not be changed")
+	}
+
+	return r.create(with(ctx))
+}
+
+func (r Revision) create(c conn) (*Revision, error) {
+	var revision Revision
+	err := c.get(&revision, `
+		insert into revision (note, token, text)
+		values($1, NULLIF($2, 0), $3)
+		RETURNING `+revisionColumns, r.Note, r.Token, r.Text)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &revision, nil
+}
@@ -0,0 +1,70 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package data
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevision(t *testing.T) {
+	acc := AccountNew("mail@example.com")
+	user, err := acc.Store()
+
+	assert.Nil(t, err)
+
+	token, err := TokenNew(user.ID, TokenTypeAccess).Store()
+
+	assert.Nil(t, err)
+
+	note, err := NoteNew(user.ID, "first version").StoreWithToken(token.ID)
+
+	if assert.Nil(t, err) {
+		note.Text = "second version"
+		note, err = note.Store()
+
+		assert.Nil(t, err)
+
+		note2, err2 := note.Refresh()
+		if assert.Nil(t, err2) {
+			assert.Equal(t, "second version", note2.Text)
+		}
+
+		list, err := note.GetRevisionList()
+		if assert.Nil(t, err) && assert.Equal(t, 2, len(list)) {
+			assert.Equal(t, "first version", list[0].Text)
+			assert.Equal(t, token.ID, list[0].Token)
+			assert.Equal(t, "second version", list[1].Text)
+			assert.Equal(t, 0, list[1].Token)
+
+			assert.Equal(t, []Change{{ChangeInsert, "first version"}}, list[0].Diff(nil))
+			assert.Equal(t, []Change{
+				{ChangeDelete, "first"},
+				{ChangeInsert, "second"},
+				{ChangeEqual, "version"},
+			}, list[1].Diff(list[0]))
+
+			_, err = list[0].Store()
+			assert.NotNil(t, err)
+		}
+	}
+
+	user.Remove()
+}
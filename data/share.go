@@ -0,0 +1,205 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// ShareInterface defines Share
+type ShareInterface interface {
+	IsStored() bool
+	IsValid() bool
+	Raw() string
+	Remove() error
+	RemoveContext(ctx context.Context) error
+	Store() (*Share, error)
+	StoreContext(ctx context.Context) (*Share, error)
+	View() (*Share, error)
+	ViewContext(ctx context.Context) (*Share, error)
+}
+
+// Share implements ShareInterface
+type Share struct {
+	ID       int        `db:"id"`
+	Note     int        `db:"note"`
+	Account  int        `db:"account"`
+	Text     string     `db:"text"`
+	Created  time.Time  `db:"created"`
+	Expires  *time.Time `db:"expires"`
+	MaxViews int        `db:"max_views"`
+	Views    int        `db:"views"`
+	raw      string
+}
+
+const shareColumns = "id, note, account, text, created, expires, max_views, views"
+
+// ShareNew creates a new Share of Note, optionally limited by expiry date
+// and number of views (zero for unlimited)
+func ShareNew(note int, account int, expires *time.Time, maxViews int) *Share {
+	token := random(32)
+
+	return &Share{0, note, account, shareHash(token), time.Now(), expires, maxViews, 0, token}
+}
+
+// ShareByID retrieves Share by id
+func ShareByID(id int) (*Share, error) {
+	return ShareByIDContext(context.Background(), id)
+}
+
+// ShareByIDContext is ShareByID bound to ctx
+func ShareByIDContext(ctx context.Context, id int) (*Share, error) {
+	var share Share
+
+	err := with(ctx).get(&share, `SELECT `+shareColumns+`
+		FROM share WHERE id = $1`, id)
+
+	return &share, err
+}
+
+// ShareByRaw retrieves Share by its raw token
+func ShareByRaw(raw string) (*Share, error) {
+	return ShareByRawContext(context.Background(), raw)
+}
+
+// ShareByRawContext is ShareByRaw bound to ctx
+func ShareByRawContext(ctx context.Context, raw string) (*Share, error) {
+	var share Share
+
+	err := with(ctx).get(&share, `SELECT `+shareColumns+`
+		FROM share WHERE text = $1`, shareHash(raw))
+
+	return &share, err
+}
+
+// ShareListByAccount retrieves all valid Share created by Account
+func ShareListByAccount(account int) ([]*Share, error) {
+	return ShareListByAccountContext(context.Background(), account)
+}
+
+// ShareListByAccountContext is ShareListByAccount bound to ctx
+func ShareListByAccountContext(ctx context.Context, account int) ([]*Share, error) {
+	var list []*Share
+
+	err := with(ctx).selectAll(&list, `SELECT `+shareColumns+`
+		FROM share WHERE account = $1
+		AND (expires IS NULL OR expires > now())
+		AND (max_views = 0 OR views < max_views)
+		ORDER BY id ASC`, account)
+
+	return list, err
+}
+
+// ShareListAllByAccount retrieves all Share created by Account, including
+// expired ones
+func ShareListAllByAccount(account int) ([]*Share, error) {
+	return ShareListAllByAccountContext(context.Background(), account)
+}
+
+// ShareListAllByAccountContext is ShareListAllByAccount bound to ctx
+func ShareListAllByAccountContext(ctx context.Context, account int) ([]*Share, error) {
+	var list []*Share
+
+	err := with(ctx).selectAll(&list, `SELECT `+shareColumns+`
+		FROM share WHERE account = $1 ORDER BY id ASC`, account)
+
+	return list, err
+}
+
+// shareHash hashes raw tokens; unlike passlib it is deterministic to allow
+// looking up Share by token
+func shareHash(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// IsStored checks if Share is stored in DB
+func (s Share) IsStored() bool {
+	return s.ID != 0
+}
+
+// IsValid checks if Share is neither expired nor out of views
+func (s Share) IsValid() bool {
+	if s.Expires != nil && !time.Now().Before(*s.Expires) {
+		return false
+	}
+
+	return s.MaxViews == 0 || s.Views < s.MaxViews
+}
+
+// Raw returns Share raw token
+func (s Share) Raw() string {
+	return s.raw
+}
+
+// Remove Share
+func (s Share) Remove() error {
+	return s.RemoveContext(context.Background())
+}
+
+// RemoveContext is Remove bound to ctx
+func (s Share) RemoveContext(ctx context.Context) error {
+	_, err := with(ctx).exec("delete FROM share WHERE id = $1", s.ID)
+
+	return err
+}
+
+// Store writes Share to DB
+func (s Share) Store() (*Share, error) {
+	return s.StoreContext(context.Background())
+}
+
+// StoreContext is Store bound to ctx
+func (s Share) StoreContext(ctx context.Context) (*Share, error) {
+	var share Share
+	err := with(ctx).get(&share, `
+		insert into share (note, account, text, expires, max_views)
+		values($1, $2, $3, $4, $5)
+		RETURNING `+shareColumns, s.Note, s.Account, s.Text, s.Expires, s.MaxViews)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &share, nil
+}
+
+// View counts a view of Share and fails if it is no longer valid
+func (s Share) View() (*Share, error) {
+	return s.ViewContext(context.Background())
+}
+
+// ViewContext is View bound to ctx
+func (s Share) ViewContext(ctx context.Context) (*Share, error) {
+	err := with(ctx).get(&s.Views, `UPDATE share SET views = views + 1
+		WHERE id = $1
+		AND (expires IS NULL OR expires > now())
+		AND (max_views = 0 OR views < max_views)
+		RETURNING views`, s.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
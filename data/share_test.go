@@ -0,0 +1,104 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShare(t *testing.T) {
+	acc := AccountNew("mail@example.com")
+	user, err := acc.Store()
+
+	assert.Nil(t, err)
+
+	note, err := NoteNew(user.ID, "shared content").Store()
+
+	assert.Nil(t, err)
+
+	share := ShareNew(note.ID, user.ID, nil, 2)
+
+	assert.NotEqual(t, "", share.Raw())
+	assert.NotEqual(t, share.Raw(), share.Text)
+	assert.False(t, share.IsStored())
+	assert.True(t, share.IsValid())
+
+	raw := share.Raw()
+	share, err = share.Store()
+
+	if assert.Nil(t, err) {
+		assert.True(t, share.IsStored())
+
+		found, err := ShareByRaw(raw)
+		if assert.Nil(t, err) {
+			assert.Equal(t, share.ID, found.ID)
+			assert.Equal(t, note.ID, found.Note)
+		}
+
+		_, err = ShareByRaw("test" + raw)
+		assert.NotNil(t, err)
+
+		share, err = share.View()
+		if assert.Nil(t, err) {
+			assert.Equal(t, 1, share.Views)
+		}
+
+		share, err = share.View()
+		if assert.Nil(t, err) {
+			assert.Equal(t, 2, share.Views)
+			assert.False(t, share.IsValid())
+		}
+
+		_, err = share.View()
+		assert.NotNil(t, err)
+
+		list, err := ShareListByAccount(user.ID)
+		if assert.Nil(t, err) {
+			assert.Equal(t, 0, len(list))
+		}
+	}
+
+	past := time.Now().Add(-time.Hour)
+	expired := ShareNew(note.ID, user.ID, &past, 0)
+
+	assert.False(t, expired.IsValid())
+
+	expired, err = expired.Store()
+	if assert.Nil(t, err) {
+		_, err = expired.View()
+		assert.NotNil(t, err)
+	}
+
+	future := time.Now().Add(time.Hour)
+	active, err := ShareNew(note.ID, user.ID, &future, 0).Store()
+
+	if assert.Nil(t, err) {
+		list, err := ShareListByAccount(user.ID)
+		if assert.Nil(t, err) && assert.Equal(t, 1, len(list)) {
+			assert.Equal(t, active.ID, list[0].ID)
+		}
+
+		assert.Nil(t, active.Remove())
+	}
+
+	user.Remove()
+}
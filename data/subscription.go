@@ -18,71 +18,215 @@
 
 package data
 
-import "time"
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
 
 // SubscriptionInterface defines Subscription
 type SubscriptionInterface interface {
 	Activate() (*Subscription, error)
+	ActivateContext(ctx context.Context) (*Subscription, error)
 	Deactivate() (*Subscription, error)
+	DeactivateContext(ctx context.Context) (*Subscription, error)
+	EndGrace() (*Subscription, error)
+	EndGraceContext(ctx context.Context) (*Subscription, error)
+	InGrace() bool
+	InTrial() bool
 	IsStored() bool
+	IsTrial() bool
+	IsValid() bool
 	Refresh() (*Subscription, error)
+	RefreshContext(ctx context.Context) (*Subscription, error)
+	Remind() (*Subscription, error)
+	RemindContext(ctx context.Context) (*Subscription, error)
+	StartGrace(end time.Time) (*Subscription, error)
+	StartGraceContext(ctx context.Context, end time.Time) (*Subscription, error)
 	Store() (*Subscription, error)
+	StoreContext(ctx context.Context) (*Subscription, error)
+	StoreTx(tx *sqlx.Tx) (*Subscription, error)
 
-	create() (SubscriptionInterface, error)
-	update() (SubscriptionInterface, error)
+	create(c conn) (SubscriptionInterface, error)
+	update(c conn) (SubscriptionInterface, error)
 }
 
 // Subscription implements SubscriptionInterface
 type Subscription struct {
-	ID       int       `db:"id"`
-	Account  int       `db:"account"`
-	Created  time.Time `db:"created"`
-	StripeID string    `db:"stripeid"`
-	Active   bool      `db:"active"`
+	ID       int        `db:"id"`
+	Account  int        `db:"account"`
+	Created  time.Time  `db:"created"`
+	StripeID string     `db:"stripeid"`
+	Active   bool       `db:"active"`
+	TrialEnd *time.Time `db:"trial_end"`
+	GraceEnd *time.Time `db:"grace_end"`
+	Reminded *time.Time `db:"reminded"`
 }
 
+const subscriptionColumns = `id, account, created, COALESCE(stripeid, '') AS stripeid,
+	active, trial_end, grace_end, reminded`
+
 // SubscriptionNew creates a new Subscription
 func SubscriptionNew(account int, stripeid string) *Subscription {
-	return &Subscription{0, account, time.Now(), stripeid, false}
+	return &Subscription{0, account, time.Now(), stripeid, false, nil, nil, nil}
+}
+
+// SubscriptionTrialNew creates a new active trial Subscription ending at end
+func SubscriptionTrialNew(account int, end time.Time) *Subscription {
+	return &Subscription{0, account, time.Now(), "", true, &end, nil, nil}
 }
 
 // SubscriptionByID retrieves Subscription by id
 func SubscriptionByID(id int) (*Subscription, error) {
+	return SubscriptionByIDContext(context.Background(), id)
+}
+
+// SubscriptionByIDContext is SubscriptionByID bound to ctx
+func SubscriptionByIDContext(ctx context.Context, id int) (*Subscription, error) {
+	return subscriptionByID(with(ctx), id)
+}
+
+func subscriptionByID(c conn, id int) (*Subscription, error) {
 	var sub Subscription
 
-	err := db.Get(&sub, "SELECT id, account, created, stripeid, active FROM subscription WHERE id = $1", id)
+	err := c.get(&sub, "SELECT "+subscriptionColumns+" FROM subscription WHERE id = $1", id)
 
 	return &sub, err
 }
 
-// SubscriptionByAccountID retrieves Subscription by Account id
+// SubscriptionByAccountID retrieves the latest active Subscription by Account id
 func SubscriptionByAccountID(id int) (*Subscription, error) {
+	return SubscriptionByAccountIDContext(context.Background(), id)
+}
+
+// SubscriptionByAccountIDContext is SubscriptionByAccountID bound to ctx
+func SubscriptionByAccountIDContext(ctx context.Context, id int) (*Subscription, error) {
 	var sub Subscription
 
-	err := db.Select(&sub, `SELECT id, account, created, stripeid, active
-		FROM subscription WHERE account = $1 AND active = TRUE`, id)
+	err := with(ctx).get(&sub, `SELECT `+subscriptionColumns+`
+		FROM subscription WHERE account = $1 AND active = TRUE
+		ORDER BY id DESC LIMIT 1`, id)
 
 	return &sub, err
 }
 
+// SubscriptionListByAccount retrieves all Subscription of Account
+func SubscriptionListByAccount(account int) ([]*Subscription, error) {
+	return SubscriptionListByAccountContext(context.Background(), account)
+}
+
+// SubscriptionListByAccountContext is SubscriptionListByAccount bound to ctx
+func SubscriptionListByAccountContext(ctx context.Context, account int) ([]*Subscription, error) {
+	var list []*Subscription
+
+	err := with(ctx).selectAll(&list, `SELECT `+subscriptionColumns+`
+		FROM subscription WHERE account = $1 ORDER BY id ASC`, account)
+
+	return list, err
+}
+
+// SubscriptionListActive retrieves all active Subscription
+func SubscriptionListActive() ([]*Subscription, error) {
+	return SubscriptionListActiveContext(context.Background())
+}
+
+// SubscriptionListActiveContext is SubscriptionListActive bound to ctx
+func SubscriptionListActiveContext(ctx context.Context) ([]*Subscription, error) {
+	var list []*Subscription
+
+	err := with(ctx).selectAll(&list, `SELECT `+subscriptionColumns+`
+		FROM subscription WHERE active = TRUE ORDER BY id ASC`)
+
+	return list, err
+}
+
 // Activate activates Subscripiton and updates the DB
 func (s Subscription) Activate() (*Subscription, error) {
+	return s.ActivateContext(context.Background())
+}
+
+// ActivateContext is Activate bound to ctx
+func (s Subscription) ActivateContext(ctx context.Context) (*Subscription, error) {
 	if s.Active {
 		return &s, nil
 	}
 
 	s.Active = true
-	return s.Store()
+	return s.StoreContext(ctx)
 }
 
 // Deactivate deactivates Subscrition and updates the DB
 func (s Subscription) Deactivate() (*Subscription, error) {
+	return s.DeactivateContext(context.Background())
+}
+
+// DeactivateContext is Deactivate bound to ctx
+func (s Subscription) DeactivateContext(ctx context.Context) (*Subscription, error) {
 	if !s.Active {
 		return &s, nil
 	}
 
 	s.Active = false
-	return s.Store()
+	return s.StoreContext(ctx)
+}
+
+// StartGrace keeps access to Subscription until end after a failed payment
+func (s Subscription) StartGrace(end time.Time) (*Subscription, error) {
+	return s.StartGraceContext(context.Background(), end)
+}
+
+// StartGraceContext is StartGrace bound to ctx
+func (s Subscription) StartGraceContext(ctx context.Context, end time.Time) (*Subscription, error) {
+	s.GraceEnd = &end
+	s.Reminded = nil
+
+	return s.StoreContext(ctx)
+}
+
+// EndGrace removes the grace period after a successful payment
+func (s Subscription) EndGrace() (*Subscription, error) {
+	return s.EndGraceContext(context.Background())
+}
+
+// EndGraceContext is EndGrace bound to ctx
+func (s Subscription) EndGraceContext(ctx context.Context) (*Subscription, error) {
+	if s.GraceEnd == nil {
+		return &s, nil
+	}
+
+	s.GraceEnd = nil
+	s.Reminded = nil
+
+	return s.StoreContext(ctx)
+}
+
+// Remind marks that a reminder mail has been sent for Subscription
+func (s Subscription) Remind() (*Subscription, error) {
+	return s.RemindContext(context.Background())
+}
+
+// RemindContext is Remind bound to ctx
+func (s Subscription) RemindContext(ctx context.Context) (*Subscription, error) {
+	now := time.Now()
+	s.Reminded = &now
+
+	return s.StoreContext(ctx)
+}
+
+// IsTrial checks if Subscription is a trial without payment
+func (s Subscription) IsTrial() bool {
+	return s.StripeID == "" && s.TrialEnd != nil
+}
+
+// InTrial checks if Subscription trial has not ended yet
+func (s Subscription) InTrial() bool {
+	return s.TrialEnd != nil && time.Now().Before(*s.TrialEnd)
+}
+
+// InGrace checks if Subscription is in grace period after a failed payment
+func (s Subscription) InGrace() bool {
+	return s.GraceEnd != nil && time.Now().Before(*s.GraceEnd)
 }
 
 // IsStored checks if Subscription is stored in DB
@@ -90,45 +234,74 @@ func (s Subscription) IsStored() bool {
 	return s.ID != 0
 }
 
+// IsValid checks if Subscription grants access to the paid plan
+func (s Subscription) IsValid() bool {
+	if !s.Active {
+		return false
+	}
+
+	if s.IsTrial() {
+		return s.InTrial()
+	}
+
+	return s.GraceEnd == nil || s.InGrace()
+}
+
 // Refresh Subscription from DB
 func (s Subscription) Refresh() (*Subscription, error) {
-	return SubscriptionByID(s.ID)
+	return s.RefreshContext(context.Background())
+}
+
+// RefreshContext is Refresh bound to ctx
+func (s Subscription) RefreshContext(ctx context.Context) (*Subscription, error) {
+	return SubscriptionByIDContext(ctx, s.ID)
 }
 
 // Store writes Subscription to DB
 func (s Subscription) Store() (*Subscription, error) {
+	return s.StoreContext(context.Background())
+}
+
+// StoreContext is Store bound to ctx
+func (s Subscription) StoreContext(ctx context.Context) (*Subscription, error) {
+	return s.store(with(ctx))
+}
+
+// StoreTx writes Subscription to DB as part of transaction tx
+func (s Subscription) StoreTx(tx *sqlx.Tx) (*Subscription, error) {
+	return s.store(withTx(tx))
+}
+
+func (s Subscription) store(c conn) (*Subscription, error) {
 	if s.IsStored() {
-		return s.update()
+		return s.update(c)
 	}
 
-	return s.create()
+	return s.create(c)
 }
 
-func (s Subscription) create() (*Subscription, error) {
-	var id int
-	rows, err := db.Query(`
-		insert into subscription (account, stripeid)
-		values($1, $2)
-		RETURNING id
-	`, s.Account, s.StripeID)
+func (s Subscription) create(c conn) (*Subscription, error) {
+	var sub Subscription
+	err := c.get(&sub, `
+		insert into subscription (account, stripeid, trial_end, active)
+		values($1, NULLIF($2, ''), $3, $4)
+		RETURNING `+subscriptionColumns, s.Account, s.StripeID, s.TrialEnd, s.Active)
 
 	if err != nil {
 		return nil, err
 	}
 
-	rows.Next()
-	rows.Scan(&id)
-
-	return SubscriptionByID(id)
+	return &sub, nil
 }
 
-func (s Subscription) update() (*Subscription, error) {
-	_, err := db.Query(`UPDATE subscription SET active = $2
-		WHERE id = $1`, s.ID, s.Active)
+func (s Subscription) update(c conn) (*Subscription, error) {
+	var sub Subscription
+	err := c.get(&sub, `UPDATE subscription SET active = $2, grace_end = $3, reminded = $4
+		WHERE id = $1 RETURNING `+subscriptionColumns, s.ID, s.Active, s.GraceEnd, s.Reminded)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return &s, nil
+	return &sub, nil
 }
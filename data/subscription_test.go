@@ -20,6 +20,7 @@ package data
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -68,3 +69,82 @@ func TestSubscription(t *testing.T) {
 
 	user.Remove()
 }
+
+func TestSubscriptionTrial(t *testing.T) {
+	acc := AccountNew("lorem@example.com")
+	user, err := acc.Store()
+
+	assert.Nil(t, err)
+
+	sub := SubscriptionTrialNew(user.ID, time.Now().Add(time.Hour))
+
+	assert.True(t, sub.IsTrial())
+	assert.True(t, sub.InTrial())
+	assert.True(t, sub.Active)
+	assert.True(t, sub.IsValid())
+
+	sub, err = sub.Store()
+
+	if assert.Nil(t, err) {
+		assert.Equal(t, "", sub.StripeID)
+		assert.True(t, sub.IsTrial())
+		assert.True(t, sub.IsValid())
+		assert.True(t, user.HasSubscription())
+
+		sub, err = sub.Remind()
+		if assert.Nil(t, err) {
+			assert.NotNil(t, sub.Reminded)
+		}
+	}
+
+	expired := SubscriptionTrialNew(user.ID, time.Now().Add(-time.Hour))
+	expired, err = expired.Store()
+
+	if assert.Nil(t, err) {
+		assert.False(t, expired.InTrial())
+		assert.False(t, expired.IsValid())
+		assert.False(t, user.HasSubscription())
+	}
+
+	user.Remove()
+}
+
+func TestSubscriptionGrace(t *testing.T) {
+	acc := AccountNew("lorem@example.com")
+	user, err := acc.Store()
+
+	assert.Nil(t, err)
+
+	sub := SubscriptionNew(user.ID, "test-grace")
+	sub, err = sub.Store()
+
+	if assert.Nil(t, err) {
+		sub, err = sub.Activate()
+
+		assert.Nil(t, err)
+		assert.False(t, sub.IsTrial())
+		assert.True(t, sub.IsValid())
+
+		sub, err = sub.StartGrace(time.Now().Add(time.Hour))
+		if assert.Nil(t, err) {
+			assert.True(t, sub.InGrace())
+			assert.True(t, sub.IsValid())
+			assert.True(t, user.HasSubscription())
+		}
+
+		sub, err = sub.StartGrace(time.Now().Add(-time.Hour))
+		if assert.Nil(t, err) {
+			assert.False(t, sub.InGrace())
+			assert.False(t, sub.IsValid())
+			assert.False(t, user.HasSubscription())
+		}
+
+		sub, err = sub.EndGrace()
+		if assert.Nil(t, err) {
+			assert.Nil(t, sub.GraceEnd)
+			assert.True(t, sub.IsValid())
+		}
+	}
+
+	user.Remove()
+}
@@ -19,8 +19,10 @@
 package data
 
 import (
+	"context"
 	"time"
 
+	"github.com/jmoiron/sqlx"
 	"gopkg.in/hlandau/passlib.v1"
 )
 
@@ -29,17 +31,24 @@ const (
 	TokenTypeMaintenace = 1
 	// TokenTypeAccess defines access tokens
 	TokenTypeAccess = 2
+	// TokenTypeDeletion defines tokens confirming the deletion of an account
+	TokenTypeDeletion = 3
 )
 
 // TokenInterface defines Token
 type TokenInterface interface {
 	Activate() (Token, error)
+	ActivateContext(ctx context.Context) (Token, error)
 	Deactivate() (Token, error)
+	DeactivateContext(ctx context.Context) (Token, error)
 	IsSecure() bool
 	Matches(raw string) bool
 	Raw() string
 	Remove() error
+	RemoveContext(ctx context.Context) error
 	Store() (Token, error)
+	StoreContext(ctx context.Context) (Token, error)
+	StoreTx(tx *sqlx.Tx) (Token, error)
 }
 
 // Token implements TokenInterface
@@ -53,6 +62,8 @@ type Token struct {
 	raw     string
 }
 
+const tokenColumns = "id, account, text, created, type, active"
+
 // TokenNew creates a new Token
 func TokenNew(account int, tokenType int) *Token {
 	token := random(32)
@@ -63,41 +74,65 @@ func TokenNew(account int, tokenType int) *Token {
 
 // TokenByID retrieves Token by id
 func TokenByID(id int) (*Token, error) {
+	return TokenByIDContext(context.Background(), id)
+}
+
+// TokenByIDContext is TokenByID bound to ctx
+func TokenByIDContext(ctx context.Context, id int) (*Token, error) {
+	return tokenByID(with(ctx), id)
+}
+
+func tokenByID(c conn, id int) (*Token, error) {
 	var token Token
 
-	err := db.Get(&token, "SELECT id, account, text, created, type, active FROM token WHERE id = $1", id)
+	err := c.get(&token, "SELECT "+tokenColumns+" FROM token WHERE id = $1", id)
 
 	return &token, err
 }
 
 // TokenListByAccountAndType retrieves Token list by Account and type
-func TokenListByAccountAndType(account int, tType int) []*Token {
+func TokenListByAccountAndType(account int, tType int) ([]*Token, error) {
+	return TokenListByAccountAndTypeContext(context.Background(), account, tType)
+}
+
+// TokenListByAccountAndTypeContext is TokenListByAccountAndType bound to ctx
+func TokenListByAccountAndTypeContext(ctx context.Context, account int, tType int) ([]*Token, error) {
 	var list []*Token
 
-	db.Select(&list, `SELECT id, account, text, created, type, active
+	err := with(ctx).selectAll(&list, `SELECT `+tokenColumns+`
 		FROM token WHERE account = $1 AND type = $2`, account, tType)
 
-	return list
+	return list, err
 }
 
 // Activate activates Token and updates the DB
 func (t Token) Activate() (*Token, error) {
+	return t.ActivateContext(context.Background())
+}
+
+// ActivateContext is Activate bound to ctx
+func (t Token) ActivateContext(ctx context.Context) (*Token, error) {
 	if t.Active {
 		return &t, nil
 	}
 
 	t.Active = true
-	return t.Store()
+	return t.StoreContext(ctx)
 }
 
 // Deactivate activates Token and updates the DB
 func (t Token) Deactivate() (*Token, error) {
+	return t.DeactivateContext(context.Background())
+}
+
+// DeactivateContext is Deactivate bound to ctx
+func (t Token) DeactivateContext(ctx context.Context) (*Token, error) {
 	if !t.Active {
 		return &t, nil
 	}
 
 	t.Active = false
-	return t.Store()
+	return t.StoreContext(ctx)
 }
 
 // IsSecure checks Token is secure
@@ -128,45 +163,62 @@ func (t Token) Raw() string {
 
 // Remove Token
 func (t Token) Remove() error {
-	_, err := db.Exec("delete FROM token WHERE id = $1", t.ID)
+	return t.RemoveContext(context.Background())
+}
+
+// RemoveContext is Remove bound to ctx
+func (t Token) RemoveContext(ctx context.Context) error {
+	_, err := with(ctx).exec("delete FROM token WHERE id = $1", t.ID)
 
 	return err
 }
 
 // Store writes Token to DB
 func (t Token) Store() (*Token, error) {
+	return t.StoreContext(context.Background())
+}
+
+// StoreContext is Store bound to ctx
+func (t Token) StoreContext(ctx context.Context) (*Token, error) {
+	return t.store(with(ctx))
+}
+
+// StoreTx writes Token to DB as part of transaction tx
+func (t Token) StoreTx(tx *sqlx.Tx) (*Token, error) {
+	return t.store(withTx(tx))
+}
+
+func (t Token) store(c conn) (*Token, error) {
 	if t.IsStored() {
-		return t.update()
+		return t.update(c)
 	}
 
-	return t.create()
+	return t.create(c)
 }
 
-func (t Token) create() (*Token, error) {
-	var id int
-	rows, err := db.Query(`
+func (t Token) create(c conn) (*Token, error) {
+	var token Token
+	err := c.get(&token, `
 		insert into token (account, text, type, active)
 		values($1, $2, $3, $4)
-		RETURNING id
-	`, t.Account, t.Text, t.Type, t.Active)
+		RETURNING `+tokenColumns, t.Account, t.Text, t.Type, t.Active)
 
 	if err != nil {
 		return nil, err
 	}
 
-	rows.Next()
-	rows.Scan(&id)
-
-	return TokenByID(id)
+	return &token, nil
 }
 
-func (t Token) update() (*Token, error) {
-	_, err := db.Query(`UPDATE token SET text = $2, active = $3
-		WHERE id = $1`, t.ID, t.Text, t.Active)
+func (t Token) update(c conn) (*Token, error) {
+	var token Token
+	err := c.get(&token, `UPDATE token SET text = $2, active = $3
+		WHERE id = $1 RETURNING `+tokenColumns, t.ID, t.Text, t.Active)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return &t, nil
+	token.raw = t.raw
+	return &token, nil
 }
@@ -97,11 +97,23 @@ func TestTokenList(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotNil(t, token3.ID)
 
-	listMaintenance := TokenListByAccountAndType(user.ID, TokenTypeMaintenace)
+	token4 := TokenNew(user.ID, TokenTypeDeletion)
+	token4, err = token4.Store()
+
+	assert.Nil(t, err)
+	assert.NotNil(t, token4.ID)
+
+	listMaintenance, err := TokenListByAccountAndType(user.ID, TokenTypeMaintenace)
+	assert.Nil(t, err)
 	assert.Equal(t, 2, len(listMaintenance))
 
-	listAccess := TokenListByAccountAndType(user.ID, TokenTypeAccess)
+	listAccess, err := TokenListByAccountAndType(user.ID, TokenTypeAccess)
+	assert.Nil(t, err)
 	assert.Equal(t, 1, len(listAccess))
 
+	listDeletion, err := TokenListByAccountAndType(user.ID, TokenTypeDeletion)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(listDeletion))
+
 	user.Remove()
 }
@@ -0,0 +1,141 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package logger writes leveled log lines in logfmt, which Heroku and most
+// log services parse into fields
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the severity of a log line
+type Level int
+
+// Available levels, lines below the configured level are dropped
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+var (
+	mutex  sync.Mutex
+	output io.Writer = os.Stdout
+	level            = LevelInfo
+)
+
+// Fields are additional key value pairs of a log line
+type Fields map[string]interface{}
+
+// SetOutput sets the writer receiving log lines
+func SetOutput(w io.Writer) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	output = w
+}
+
+// SetLevel sets the lowest level written
+func SetLevel(l Level) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	level = l
+}
+
+// ParseLevel returns the Level named name, an empty name is LevelInfo
+func ParseLevel(name string) (Level, error) {
+	if name == "" {
+		return LevelInfo, nil
+	}
+
+	for l, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return l, nil
+		}
+	}
+
+	return LevelInfo, fmt.Errorf("Unknown log level %q", name)
+}
+
+// Debug writes msg with fields at LevelDebug
+func Debug(msg string, fields Fields) {
+	Log(LevelDebug, msg, fields)
+}
+
+// Info writes msg with fields at LevelInfo
+func Info(msg string, fields Fields) {
+	Log(LevelInfo, msg, fields)
+}
+
+// Warn writes msg with fields at LevelWarn
+func Warn(msg string, fields Fields) {
+	Log(LevelWarn, msg, fields)
+}
+
+// Error writes msg with fields at LevelError
+func Error(msg string, fields Fields) {
+	Log(LevelError, msg, fields)
+}
+
+// Log writes msg with fields at l, fields are sorted by key
+func Log(l Level, msg string, fields Fields) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if l < level {
+		return
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	line := "time=" + time.Now().UTC().Format(time.RFC3339Nano) + " level=" + levelNames[l] + " msg=" + quote(msg)
+	for _, key := range keys {
+		line += " " + key + "=" + quote(fmt.Sprint(fields[key]))
+	}
+
+	io.WriteString(output, line+"\n")
+}
+
+// quote quotes value if it contains spaces, quotes or equal signs
+func quote(value string) string {
+	if value == "" || strings.ContainsAny(value, " \"=\n\t") {
+		return fmt.Sprintf("%q", value)
+	}
+
+	return value
+}
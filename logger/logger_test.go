@@ -0,0 +1,60 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLog(t *testing.T) {
+	var out bytes.Buffer
+	SetOutput(&out)
+	defer SetOutput(os.Stdout)
+
+	SetLevel(LevelInfo)
+	Debug("hidden", nil)
+	Info("request", Fields{"status": 200, "route": "/v1/notes"})
+	Error("request failed", Fields{"error": errors.New("pq: connection refused")})
+
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	if !assert.Len(t, lines, 2) {
+		return
+	}
+
+	assert.Contains(t, string(lines[0]), " level=info msg=request route=/v1/notes status=200")
+	assert.Contains(t, string(lines[1]), ` level=error msg="request failed" error="pq: connection refused"`)
+}
+
+func TestParseLevel(t *testing.T) {
+	l, err := ParseLevel("WARN")
+	assert.Nil(t, err)
+	assert.Equal(t, LevelWarn, l)
+
+	l, err = ParseLevel("")
+	assert.Nil(t, err)
+	assert.Equal(t, LevelInfo, l)
+
+	_, err = ParseLevel("loud")
+	assert.NotNil(t, err)
+}
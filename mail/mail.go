@@ -0,0 +1,77 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package mail
+
+import (
+	"strconv"
+
+	"github.com/clinotes/server/metrics"
+	"github.com/keighl/postmark"
+)
+
+var (
+	client  *postmark.Client
+	from    string
+	replyTo string
+
+	metricMails = metrics.NewCounter(
+		"clinotes_mails_total", "Mails handed to Postmark by template and result.",
+		"template", "result",
+	)
+)
+
+// Configure sets up the Postmark client and sender addresses
+func Configure(token string, sender string, reply string) {
+	client = postmark.NewClient(token, "")
+	from = sender
+	replyTo = reply
+}
+
+// Send sends a mail using a Postmark template
+func Send(to string, template int64, model map[string]interface{}) error {
+	_, err := client.SendTemplatedEmail(postmark.TemplatedEmail{
+		TemplateId:    template,
+		TemplateModel: model,
+		From:          from,
+		To:            to,
+		ReplyTo:       replyTo,
+	})
+
+	result := "sent"
+	if err != nil {
+		result = "failed"
+	}
+
+	metricMails.Inc(strconv.FormatInt(template, 10), result)
+	return err
+}
+
+// Ping checks that Postmark is reachable and accepts the configured token
+func Ping() error {
+	_, _, err := client.GetTemplates(1, 0)
+
+	return err
+}
+
+// SendToken sends a mail containing a token using a Postmark template
+func SendToken(to string, token string, template int64) error {
+	return Send(to, template, map[string]interface{}{
+		"token": token,
+	})
+}
@@ -0,0 +1,173 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package mail
+
+import (
+	"time"
+
+	"github.com/clinotes/server/data"
+	"github.com/clinotes/server/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// outboxBatch is the number of messages delivered per run
+	outboxBatch = 50
+	// outboxLease keeps claimed messages from other workers while sending
+	outboxLease = 5 * time.Minute
+	// outboxBackoff is the delay after the first failed attempt, it doubles
+	// with every further attempt up to outboxMaxBackoff
+	outboxBackoff    = 30 * time.Second
+	outboxMaxBackoff = 6 * time.Hour
+	// outboxMaxAttempts is the number of attempts before a message is
+	// dead-lettered and left for an admin to requeue
+	outboxMaxAttempts = 10
+)
+
+var (
+	wake = make(chan struct{}, 1)
+	quit chan struct{}
+	done chan struct{}
+)
+
+// Queue writes a mail to the outbox and triggers its delivery
+func Queue(to string, template int64, model map[string]interface{}) error {
+	_, err := data.MessageNew(to, template, model).Store()
+
+	if err == nil {
+		Wake()
+	}
+
+	return err
+}
+
+// QueueToken writes a mail containing a token to the outbox and triggers
+// its delivery
+func QueueToken(to string, token string, template int64) error {
+	return Queue(to, template, map[string]interface{}{
+		"token": token,
+	})
+}
+
+// QueueTx writes a mail to the outbox as part of transaction tx, it is
+// delivered once tx has been committed
+func QueueTx(tx *sqlx.Tx, to string, template int64, model map[string]interface{}) error {
+	_, err := data.MessageNew(to, template, model).StoreTx(tx)
+
+	return err
+}
+
+// QueueTokenTx writes a mail containing a token to the outbox as part of
+// transaction tx
+func QueueTokenTx(tx *sqlx.Tx, to string, token string, template int64) error {
+	return QueueTx(tx, to, template, map[string]interface{}{
+		"token": token,
+	})
+}
+
+// StartOutbox delivers pending messages every interval and whenever Wake is
+// called
+func StartOutbox(interval time.Duration) {
+	quit = make(chan struct{})
+	done = make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		defer close(done)
+
+		for {
+			deliver()
+
+			select {
+			case <-ticker.C:
+			case <-wake:
+			case <-quit:
+				return
+			}
+		}
+	}()
+}
+
+// StopOutbox ends delivery and waits for a running batch to finish, claimed
+// but unsent messages are picked up again after their lease expires
+func StopOutbox() {
+	if quit == nil {
+		return
+	}
+
+	close(quit)
+	<-done
+	quit = nil
+}
+
+// Wake triggers delivery of pending messages without waiting for the next run
+func Wake() {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+func deliver() {
+	list, err := data.MessageClaimPending(outboxBatch, outboxLease)
+	if err != nil {
+		logger.Error("Failed to read outbox", logger.Fields{"error": err})
+		return
+	}
+
+	for _, message := range list {
+		err := Send(message.Address, message.Template, message.GetModel())
+		fields := logger.Fields{"message": message.ID, "template": message.Template, "attempts": message.Attempts + 1}
+
+		switch {
+		case err == nil:
+			_, err = message.MarkSent()
+		case message.Attempts+1 >= outboxMaxAttempts:
+			fields["error"] = err
+			logger.Error("Giving up on message", fields)
+			_, err = message.MarkDead(err.Error())
+		default:
+			fields["error"] = err
+			logger.Warn("Failed to send message", fields)
+			_, err = message.MarkFailed(err.Error(), time.Now().Add(backoff(message.Attempts)))
+		}
+
+		if err != nil {
+			fields["error"] = err
+			logger.Error("Failed to update outbox", fields)
+		}
+	}
+}
+
+// backoff returns the delay before retrying a message after attempts
+// previous failures
+func backoff(attempts int) time.Duration {
+	delay := outboxBackoff
+
+	for i := 0; i < attempts && delay < outboxMaxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > outboxMaxBackoff {
+		return outboxMaxBackoff
+	}
+
+	return delay
+}
@@ -19,126 +19,127 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"io/ioutil"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/clinotes/server/config"
 	"github.com/clinotes/server/data"
+	"github.com/clinotes/server/logger"
+	"github.com/clinotes/server/mail"
 	"github.com/clinotes/server/route"
+	"github.com/clinotes/server/schedule"
 	"github.com/gorilla/mux"
 	"github.com/jackc/pgx"
 	_ "github.com/jackc/pgx/stdlib"
 	"github.com/jmoiron/sqlx"
-	"github.com/keighl/postmark"
-	"github.com/spf13/viper"
 )
 
 var (
 	version                = "0.0.6"
 	versionClientSupported = "0.2.0"
+	versionClientLatest    = "0.2.0"
 
-	pmark *postmark.Client
-
-	maxDBConnections int
-	connectionURL    string
-	postmarkAPIToken string
-
-	httpHostname string
-	httpPort     string
-
-	postmarkTemplateIDWelcome int64
-	postmarkTemplateIDConfirm int64
-	postmarkTemplateIDToken   int64
-	postmarkFrom              string
-	postmarkReplyTo           string
-
+	conf   *config.Config
 	router *mux.Router
 )
 
-func readEnvironment() {
-	viper.AutomaticEnv()
-	viper.ReadInConfig()
-
-	connectionURL = viper.GetString("DATABASE_URL")
-	postmarkAPIToken = viper.GetString("POSTMARK_API_KEY")
-
-	postmarkTemplateIDWelcome = viper.GetInt64("POSTMARK_TEMPLATE_WELCOME")
-	postmarkTemplateIDConfirm = viper.GetInt64("POSTMARK_TEMPLATE_CONFIRM")
-	postmarkTemplateIDToken = viper.GetInt64("POSTMARK_TEMPLATE_TOKEN")
-	postmarkFrom = viper.GetString("POSTMARK_FROM")
-	postmarkReplyTo = viper.GetString("POSTMARK_REPLY_TO")
-}
-
-func checkEnvironment() {
-	if postmarkTemplateIDWelcome <= 0 {
-		fmt.Println("Please set POSTMARK_TEMPLATE_WELCOME > 0")
+// connect opens the data pool
+func connect() {
+	if conf.DatabaseURL == "" {
+		logger.Error("Please set DATABASE_URL", nil)
 		os.Exit(1)
 	}
 
-	if postmarkTemplateIDConfirm <= 0 {
-		fmt.Println("Please set POSTMARK_TEMPLATE_CONFIRM > 0")
-		os.Exit(1)
-	}
+	db, err := sqlx.Open("pgx", conf.DatabaseURL)
 
-	if postmarkTemplateIDToken <= 0 {
-		fmt.Println("Please set POSTMARK_TEMPLATE_TOKEN > 0")
+	if err != nil {
+		logger.Error("Unable to connect to database", logger.Fields{"error": err})
 		os.Exit(1)
 	}
 
-	if postmarkAPIToken == "" {
-		fmt.Println("Please set POSTMARK_API_KEY")
-		os.Exit(1)
+	if conf.MaxDBConnections > 0 {
+		db.SetMaxOpenConns(conf.MaxDBConnections)
 	}
 
-	if postmarkFrom == "" {
-		fmt.Println("Please set POSTMARK_FROM")
-		os.Exit(1)
-	}
+	data.Database(db)
+}
 
-	if postmarkReplyTo == "" {
-		fmt.Println("Please set POSTMARK_REPLY_TO")
+// setup prepares database, mail delivery and router for serving the API
+func setup() {
+	connect()
+
+	if err := data.Setup(); err != nil {
+		logger.Error("Unable to migrate database", logger.Fields{"error": err})
 		os.Exit(1)
 	}
-}
 
-func init() {
-	readEnvironment()
-	checkEnvironment()
+	// Reject signups from blocked domains, one domain per line
+	if conf.BlockedDomainsFile != "" {
+		content, err := ioutil.ReadFile(conf.BlockedDomainsFile)
 
-	// Connect to data pool
-	db, err := sqlx.Open("pgx", os.Getenv("DATABASE_URL"))
+		if err != nil {
+			logger.Error("Unable to read blocked domains", logger.Fields{"error": err, "file": conf.BlockedDomainsFile})
+			os.Exit(1)
+		}
 
-	if err != nil {
-		fmt.Println("Unable to connect to database", err)
-		os.Exit(1)
+		data.BlockDomains(strings.Split(string(content), "\n"))
 	}
 
-	data.Database(db)
-	data.Setup()
+	// Configure mail delivery
+	mail.Configure(conf.PostmarkAPIKey, conf.PostmarkFrom, conf.PostmarkReplyTo)
 
 	// Create mux router
 	router = mux.NewRouter()
 	api := router.PathPrefix("/").Subrouter()
 
-	config := route.Configuration{
-		TemplateWelcome: postmarkTemplateIDWelcome,
-		TemplateConfirm: postmarkTemplateIDConfirm,
-		TemplateToken:   postmarkTemplateIDToken,
-		PostmarkToken:   postmarkAPIToken,
-		PostmarkFrom:    postmarkFrom,
-		PostmarkReplyTo: postmarkReplyTo,
+	routeConfig := route.Configuration{
+		TemplateWelcome: conf.TemplateWelcome,
+		TemplateConfirm: conf.TemplateConfirm,
+		TemplateToken:   conf.TemplateToken,
+		TemplateInvite:  conf.TemplateInvite,
+		TemplateDelete:  conf.TemplateDelete,
+		TemplateGoodbye: conf.TemplateGoodbye,
+		TemplateEmail:   conf.TemplateEmail,
+		TemplateNotice:  conf.TemplateNotice,
+		TrialPeriod:     conf.TrialPeriod(),
+		StripeKey:       conf.StripeAPIKey,
+		ClientMinimum:   versionClientSupported,
+		ClientLatest:    versionClientLatest,
+		AdminToken:      conf.AdminToken,
 	}
 
 	// Configure path handlers
-	for _, r := range route.Routes(config) {
-		api.Handle(r.URL, route.Handler(r.Handler)).Methods("POST")
+	versions := route.Versions(routeConfig)
+	for _, version := range versions {
+		for _, r := range version.Routes {
+			api.Handle(version.Prefix+r.URL, r).Methods(r.Method)
+		}
 	}
 
+	// Describe all routes for clients
+	api.HandleFunc("/openapi.json", route.OpenAPIHandler(versions, version)).Methods("GET")
+
+	// Prometheus metrics
+	api.HandleFunc("/metrics", route.Metrics).Methods("GET")
+
+	// Liveness and readiness probes for load balancers
+	api.HandleFunc("/healthz", route.Health).Methods("GET")
+	api.HandleFunc("/readyz", route.Ready(routeConfig)).Methods("GET")
+
+	// Public view of shared notes
+	api.HandleFunc("/s/{token}", route.ShareView).Methods("GET")
+
 	api.HandleFunc(
 		"/version",
 		func(res http.ResponseWriter, req *http.Request) {
 			res.Header().Set("Content-Type", "application/json; charset=utf-8")
-			res.Write([]byte(`{"version":"` + version + `","client":"` + versionClientSupported + `"}`))
+			res.Write([]byte(`{"version":"` + version + `","client":"` + versionClientSupported + `","latest":"` + versionClientLatest + `"}`))
 		},
 	)
 }
@@ -148,7 +149,7 @@ func prepareQueries(conn *pgx.Conn, list map[string]string) error {
 		_, err := conn.Prepare(name, query)
 
 		if err != nil {
-			fmt.Println("Failed to prepare query.", err)
+			logger.Error("Failed to prepare query", logger.Fields{"error": err, "query": name})
 			return err
 		}
 	}
@@ -156,18 +157,96 @@ func prepareQueries(conn *pgx.Conn, list map[string]string) error {
 	return nil
 }
 
-func main() {
-	// Check if running on local environment and set hostname to avoid
-	// annoying MacOS security warnings.
-	if os.Getenv("ENV") == "local" {
-		httpHostname = "localhost"
-		httpPort = "8000"
-	} else {
-		httpHostname = ""
-		httpPort = os.Getenv("PORT")
+// serve runs the API server with its background jobs until it receives
+// SIGTERM or SIGINT
+func serve() error {
+	if err := conf.Validate(); err != nil {
+		logger.Error("Unable to start server", logger.Fields{"error": err})
+		os.Exit(1)
+	}
+
+	logger.SetLevel(conf.Level())
+	setup()
+
+	// Run background jobs for trials, failed payments and unverified accounts
+	schedule.Start(schedule.Configuration{
+		Interval:         time.Hour,
+		GracePeriod:      conf.GracePeriod(),
+		UnverifiedPeriod: conf.UnverifiedPeriod(),
+		TemplateTrial:    conf.TemplateTrial,
+		TemplateGrace:    conf.TemplateGrace,
+		StripeKey:        conf.StripeAPIKey,
+	})
+
+	// Deliver queued mails from the outbox
+	mail.StartOutbox(10 * time.Second)
+
+	read, write, idle := conf.Timeouts()
+	server := &http.Server{
+		Addr:         conf.Address(),
+		Handler:      route.RequestID(route.CheckClient(route.Deadline(conf.RequestDeadline(), conf.TransferDeadline(), router))),
+		ReadTimeout:  read,
+		WriteTimeout: write,
+		IdleTimeout:  idle,
 	}
 
+	failed := make(chan error, 1)
+	go func() {
+		failed <- server.ListenAndServe()
+	}()
+
 	// Listen on PORT only on non-local environment
-	fmt.Printf("Started CLInotes API endpoint on port %s\n", httpPort)
-	http.ListenAndServe(httpHostname+":"+httpPort, router)
+	logger.Info("Started CLInotes API endpoint", logger.Fields{"address": conf.Address(), "version": version})
+
+	// Heroku sends SIGTERM on restarts, Ctrl+C sends SIGINT
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+
+	select {
+	case err := <-failed:
+		return err
+	case sig := <-signals:
+		logger.Info("Shutting down", logger.Fields{"signal": sig})
+	}
+
+	return shutdown(server)
+}
+
+// shutdown drains running requests, stops background workers and closes the
+// data pool
+func shutdown(server *http.Server) error {
+	ctx, cancel := context.WithTimeout(context.Background(), conf.ShutdownPeriod())
+	defer cancel()
+
+	err := server.Shutdown(ctx)
+	if err != nil {
+		logger.Error("Unable to finish running requests", logger.Fields{"error": err})
+	}
+
+	schedule.Stop()
+	mail.StopOutbox()
+
+	if closeErr := data.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+
+	logger.Info("Stopped CLInotes API endpoint", nil)
+	return err
+}
+
+func main() {
+	var err error
+
+	// Environment variables override values of the optional config file
+	conf, err = config.Load(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		logger.Error("Unable to read config file", logger.Fields{"error": err})
+		os.Exit(1)
+	}
+
+	// Serve the API when no command is given, e.g. on Heroku
+	if err = run(os.Args[1:]); err != nil {
+		logger.Error("Failed to run", logger.Fields{"error": err})
+		os.Exit(1)
+	}
 }
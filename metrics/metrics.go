@@ -0,0 +1,273 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package metrics collects counters, histograms and gauges and exposes them
+// in the Prometheus text format
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the upper bounds in seconds used for request and
+// database latencies
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// collector is a metric written by Handler
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+var (
+	mutex      sync.Mutex
+	collectors []collector
+)
+
+// Counter is a value that only increases, partitioned by label values
+type Counter struct {
+	name   string
+	help   string
+	labels []string
+
+	mutex  sync.Mutex
+	values map[string]float64
+}
+
+// Histogram counts observations in buckets, partitioned by label values
+type Histogram struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+
+	mutex  sync.Mutex
+	values map[string]*histogramValue
+}
+
+type histogramValue struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// Gauge is a value read by fn whenever metrics are collected
+type Gauge struct {
+	name string
+	help string
+	fn   func() float64
+}
+
+// CounterFunc is a cumulative value read by fn whenever metrics are collected
+type CounterFunc struct {
+	name string
+	help string
+	fn   func() float64
+}
+
+// NewCounter registers a Counter with the given label names
+func NewCounter(name string, help string, labels ...string) *Counter {
+	c := &Counter{name: name, help: help, labels: labels, values: map[string]float64{}}
+	register(c)
+
+	return c
+}
+
+// NewHistogram registers a Histogram using buckets as upper bounds
+func NewHistogram(name string, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{name: name, help: help, labels: labels, buckets: buckets, values: map[string]*histogramValue{}}
+	register(h)
+
+	return h
+}
+
+// NewGauge registers a Gauge reading its value from fn
+func NewGauge(name string, help string, fn func() float64) *Gauge {
+	g := &Gauge{name, help, fn}
+	register(g)
+
+	return g
+}
+
+// NewCounterFunc registers a CounterFunc reading its value from fn
+func NewCounterFunc(name string, help string, fn func() float64) *CounterFunc {
+	c := &CounterFunc{name, help, fn}
+	register(c)
+
+	return c
+}
+
+func register(c collector) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	collectors = append(collectors, c)
+}
+
+// Inc adds one to the value of the given label values
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds delta to the value of the given label values
+func (c *Counter) Add(delta float64, values ...string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.values[labelKey(values)] += delta
+}
+
+// Observe records value for the given label values
+func (h *Histogram) Observe(value float64, values ...string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	key := labelKey(values)
+	v, ok := h.values[key]
+	if !ok {
+		v = &histogramValue{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = v
+	}
+
+	for i, bound := range h.buckets {
+		if value <= bound {
+			v.counts[i]++
+		}
+	}
+
+	v.count++
+	v.sum += value
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	writeHeader(w, c.name, c.help, "counter")
+
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %v\n", c.name, labelText(c.labels, key, ""), c.values[key])
+	}
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	writeHeader(w, h.name, h.help, "histogram")
+
+	keys := make([]string, 0, len(h.values))
+	for key := range h.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		v := h.values[key]
+
+		for i, bound := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labelText(h.labels, key, fmt.Sprint(bound)), v.counts[i])
+		}
+
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labelText(h.labels, key, "+Inf"), v.count)
+		fmt.Fprintf(w, "%s_sum%s %v\n", h.name, labelText(h.labels, key, ""), v.sum)
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, labelText(h.labels, key, ""), v.count)
+	}
+}
+
+func (g *Gauge) write(w *bufio.Writer) {
+	writeHeader(w, g.name, g.help, "gauge")
+	fmt.Fprintf(w, "%s %v\n", g.name, g.fn())
+}
+
+func (c *CounterFunc) write(w *bufio.Writer) {
+	writeHeader(w, c.name, c.help, "counter")
+	fmt.Fprintf(w, "%s %v\n", c.name, c.fn())
+}
+
+// Handler writes all registered metrics in the Prometheus text format
+func Handler(w http.ResponseWriter, req *http.Request) {
+	mutex.Lock()
+	list := append([]collector{}, collectors...)
+	mutex.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	out := bufio.NewWriter(w)
+	for _, c := range list {
+		c.write(out)
+	}
+
+	out.Flush()
+}
+
+func writeHeader(w *bufio.Writer, name string, help string, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// labelKey joins label values to a map key
+func labelKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+// labelText formats the label values stored in key, le is added for
+// histogram buckets when not empty
+func labelText(names []string, key string, le string) string {
+	var pairs []string
+
+	if len(names) > 0 {
+		values := strings.Split(key, "\xff")
+
+		for i, name := range names {
+			value := ""
+			if i < len(values) {
+				value = values[i]
+			}
+
+			pairs = append(pairs, name+`="`+escape(value)+`"`)
+		}
+	}
+
+	if le != "" {
+		pairs = append(pairs, `le="`+le+`"`)
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func escape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func sortedKeys(values map[string]float64) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
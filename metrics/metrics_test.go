@@ -0,0 +1,54 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package metrics
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	counter := NewCounter("test_requests_total", "Requests handled.", "route", "status")
+	counter.Inc("/notes", "200")
+	counter.Inc("/notes", "200")
+	counter.Inc(`/say "hi"`, "500")
+
+	histogram := NewHistogram("test_duration_seconds", "Request duration.", []float64{0.1, 1})
+	histogram.Observe(0.05)
+	histogram.Observe(0.5)
+
+	NewGauge("test_connections", "Open connections.", func() float64 { return 3 })
+	NewCounterFunc("test_waits_total", "Connection waits.", func() float64 { return 7 })
+
+	res := httptest.NewRecorder()
+	Handler(res, httptest.NewRequest("GET", "/metrics", nil))
+	body := res.Body.String()
+
+	assert.Contains(t, body, "# TYPE test_requests_total counter\n")
+	assert.Contains(t, body, `test_requests_total{route="/notes",status="200"} 2`+"\n")
+	assert.Contains(t, body, `test_requests_total{route="/say \"hi\"",status="500"} 1`+"\n")
+	assert.Contains(t, body, `test_duration_seconds_bucket{le="0.1"} 1`+"\n")
+	assert.Contains(t, body, `test_duration_seconds_bucket{le="1"} 2`+"\n")
+	assert.Contains(t, body, `test_duration_seconds_bucket{le="+Inf"} 2`+"\n")
+	assert.Contains(t, body, "test_duration_seconds_count 2\n")
+	assert.Contains(t, body, "test_connections 3\n")
+	assert.Contains(t, body, "# TYPE test_waits_total counter\ntest_waits_total 7\n")
+}
@@ -0,0 +1,137 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clientVersionHeader is sent by the CLI client with its version
+const clientVersionHeader = "X-Client-Version"
+
+// Deprecation describes a route which is going to be removed
+type Deprecation struct {
+	Successor string
+	Sunset    time.Time
+}
+
+// deprecated marks route as replaced by the route at successor
+func deprecated(route Route, successor string) Route {
+	route.Deprecation = &Deprecation{Successor: successor}
+
+	return route
+}
+
+// announce sets the deprecation headers of a response
+func (d Deprecation) announce(w http.ResponseWriter) {
+	w.Header().Set("Deprecation", "true")
+
+	if d.Successor != "" {
+		w.Header().Set("Link", "<"+d.Successor+`>; rel="successor-version"`)
+	}
+
+	if !d.Sunset.IsZero() {
+		w.Header().Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+	}
+}
+
+// CheckClient rejects requests of clients older than the minimum version and
+// warns clients older than the latest version, requests without version
+// header come from clients predating it and are warned as well
+func CheckClient(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client := r.Header.Get(clientVersionHeader)
+		if r.URL.Path == "/version" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if client == "" {
+			w.Header().Set("Warning", `299 - "Please upgrade the client, it does not send its version"`)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		current, ok := parseVersion(client)
+		if !ok {
+			writeError(w, errInvalid("invalid_client_version", "Invalid client version").WithDetail("version", client))
+			return
+		}
+
+		if minimum, ok := parseVersion(conf.ClientMinimum); ok && compareVersion(current, minimum) < 0 {
+			writeError(w, errClientOutdated(client))
+			return
+		}
+
+		if latest, ok := parseVersion(conf.ClientLatest); ok && compareVersion(current, latest) < 0 {
+			w.Header().Set("Warning", `299 - "Please upgrade the client to version `+conf.ClientLatest+`"`)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func errClientOutdated(client string) *Error {
+	return newError(http.StatusUpgradeRequired, "client_outdated", "Please upgrade the client to version "+conf.ClientMinimum+" or newer").
+		WithDetail("version", client).
+		WithDetail("minimum", conf.ClientMinimum).
+		WithDetail("latest", conf.ClientLatest)
+}
+
+// parseVersion reads a version like 1.2.3, an optional leading v and
+// suffixes like -beta are ignored
+func parseVersion(version string) ([3]int, bool) {
+	var parts [3]int
+
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	version = strings.SplitN(version, "-", 2)[0]
+
+	fields := strings.Split(version, ".")
+	if len(fields) == 0 || len(fields) > 3 {
+		return parts, false
+	}
+
+	for i, field := range fields {
+		number, err := strconv.Atoi(field)
+		if err != nil || number < 0 {
+			return parts, false
+		}
+
+		parts[i] = number
+	}
+
+	return parts, true
+}
+
+func compareVersion(a [3]int, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+
+			return 1
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,111 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseVersion(t *testing.T) {
+	version, ok := parseVersion("v1.2.3-beta")
+	assert.True(t, ok)
+	assert.Equal(t, [3]int{1, 2, 3}, version)
+
+	version, ok = parseVersion("0.2")
+	assert.True(t, ok)
+	assert.Equal(t, [3]int{0, 2, 0}, version)
+
+	_, ok = parseVersion("")
+	assert.False(t, ok)
+
+	_, ok = parseVersion("1.2.3.4")
+	assert.False(t, ok)
+
+	_, ok = parseVersion("latest")
+	assert.False(t, ok)
+
+	assert.Equal(t, -1, compareVersion([3]int{0, 1, 9}, [3]int{0, 2, 0}))
+	assert.Equal(t, 0, compareVersion([3]int{0, 2, 0}, [3]int{0, 2, 0}))
+	assert.Equal(t, 1, compareVersion([3]int{1, 0, 0}, [3]int{0, 9, 9}))
+}
+
+func TestCheckClient(t *testing.T) {
+	conf = Configuration{ClientMinimum: "0.2.0", ClientLatest: "0.3.0"}
+	defer func() { conf = Configuration{} }()
+
+	handler := CheckClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	check := func(path string, version string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, nil)
+		if version != "" {
+			req.Header.Set(clientVersionHeader, version)
+		}
+
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+
+		return res
+	}
+
+	res := check("/notes", "")
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.NotEqual(t, "", res.Header().Get("Warning"))
+
+	res = check("/notes", "0.3.1")
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "", res.Header().Get("Warning"))
+
+	res = check("/notes", "0.2.5")
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.NotEqual(t, "", res.Header().Get("Warning"))
+
+	res = check("/notes", "0.1.0")
+	assert.Equal(t, http.StatusUpgradeRequired, res.Code)
+	assert.Contains(t, res.Body.String(), `"code":"client_outdated"`)
+	assert.Contains(t, res.Body.String(), `"minimum":"0.2.0"`)
+
+	res = check("/notes", "unknown")
+	assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+
+	res = check("/version", "0.1.0")
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	res = check("/version", "")
+	assert.Equal(t, "", res.Header().Get("Warning"))
+}
+
+func TestDeprecation(t *testing.T) {
+	route := deprecated(Route{"POST", "/test", nil, nil, func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
+		return nil, nil
+	}, nil}, "/v1/test")
+
+	res := httptest.NewRecorder()
+	route.ServeHTTP(res, httptest.NewRequest("POST", "/test", nil))
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "true", res.Header().Get("Deprecation"))
+	assert.Equal(t, `</v1/test>; rel="successor-version"`, res.Header().Get("Link"))
+	assert.Equal(t, "", res.Header().Get("Sunset"))
+}
@@ -0,0 +1,113 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import "net/http"
+
+// Error is an API error with HTTP status, a stable machine-readable code and
+// optional details per request field
+type Error struct {
+	Status  int
+	Code    string
+	Text    string
+	Details map[string]string
+
+	// cause is the underlying failure, it is logged but never sent to the
+	// client
+	cause error
+}
+
+// Error returns the human-readable text of Error
+func (e *Error) Error() string {
+	return e.Text
+}
+
+// WithDetail returns a copy of Error with text describing a problem of field
+func (e *Error) WithDetail(field string, text string) *Error {
+	details := map[string]string{}
+	for key, value := range e.Details {
+		details[key] = value
+	}
+	details[field] = text
+
+	return &Error{e.Status, e.Code, e.Text, details, e.cause}
+}
+
+// WithCause returns a copy of Error keeping err as underlying failure
+func (e *Error) WithCause(err error) *Error {
+	return &Error{e.Status, e.Code, e.Text, e.Details, err}
+}
+
+func newError(status int, code string, text string) *Error {
+	return &Error{status, code, text, nil, nil}
+}
+
+// errInternal is returned for failures the client cannot fix, text must not
+// expose details of the failure
+func errInternal(text string) *Error {
+	return newError(http.StatusInternalServerError, "internal_error", text)
+}
+
+// errInvalid is returned for requests with invalid field values
+func errInvalid(code string, text string) *Error {
+	return newError(http.StatusUnprocessableEntity, code, text)
+}
+
+// errNotFound is returned for unknown resources
+func errNotFound(code string, text string) *Error {
+	return newError(http.StatusNotFound, code, text)
+}
+
+// errConflict is returned for requests conflicting with the current state
+func errConflict(code string, text string) *Error {
+	return newError(http.StatusConflict, code, text)
+}
+
+var (
+	errInvalidJSON         = newError(http.StatusBadRequest, "invalid_json", "Invalid JSON data")
+	errInvalidToken        = newError(http.StatusUnauthorized, "invalid_token", "Unable to use provided token")
+	errInvalidConfirmation = newError(http.StatusUnauthorized, "invalid_confirmation", "Unable to use provided confirmation")
+	errInvalidAdminToken   = newError(http.StatusUnauthorized, "invalid_admin_token", "Invalid admin token")
+	errNotVerified         = newError(http.StatusForbidden, "account_not_verified", "Account not verified")
+	errNotAllowed          = newError(http.StatusForbidden, "not_allowed", "Not allowed in organization")
+	errTooManyRequests     = newError(http.StatusTooManyRequests, "too_many_requests", "Please wait before requesting another mail")
+	errTimeout             = newError(http.StatusServiceUnavailable, "request_timeout", "Request took too long, please try again")
+	errNotEnabled          = newError(http.StatusNotImplemented, "not_enabled", "Not enabled on this server")
+	errUnknownAccount      = errNotFound("unknown_account", "Unknown account address")
+	errUnknownOrganization = errNotFound("unknown_organization", "Unknown organization")
+	errUnknownMember       = errNotFound("unknown_member", "Unknown member")
+	errUnknownNote         = errNotFound("unknown_note", "Unknown note")
+	errUnknownRole         = errInvalid("unknown_role", "Unknown role")
+	errAddressInUse        = errConflict("address_in_use", "Address already in use")
+)
+
+// errAddress converts an address validation error of data for field
+func errAddress(field string, err error) *Error {
+	return errInvalid("invalid_address", "Invalid address").WithDetail(field, err.Error())
+}
+
+// toError converts any error returned by a Handler to an Error, errors not
+// created in this package are treated as internal failures
+func toError(err error) *Error {
+	if e, ok := err.(*Error); ok {
+		return e
+	}
+
+	return errInternal("Internal server error").WithCause(err)
+}
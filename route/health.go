@@ -0,0 +1,140 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/clinotes/server/data"
+	"github.com/clinotes/server/mail"
+	stripe "github.com/stripe/stripe-go"
+)
+
+// check is a dependency tested by the readiness endpoint, failures of
+// optional checks are reported without marking the server unready
+type check struct {
+	Name     string
+	Optional bool
+	Run      func() error
+}
+
+// APIResponseStructHealth is the result of the health endpoints
+type APIResponseStructHealth struct {
+	Status string                            `json:"status"`
+	Checks map[string]APIResponseStructCheck `json:"checks,omitempty"`
+}
+
+// APIResponseStructCheck is the result of a single check
+type APIResponseStructCheck struct {
+	Status  string  `json:"status"`
+	Latency float64 `json:"latency_ms"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// Health reports that the process is alive without touching dependencies
+func Health(w http.ResponseWriter, req *http.Request) {
+	writeHealth(w, http.StatusOK, APIResponseStructHealth{"ok", nil})
+}
+
+// Ready returns a handler checking the database and its migrations, the
+// mail and billing backends are contacted only with ?backends=true to keep
+// frequent probes from hitting external APIs. Backends and error details
+// require the admin token
+func Ready(config Configuration) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		checks := []check{
+			{"database", false, func() error { return data.PingContext(req.Context()) }},
+			{"migrations", false, func() error { return checkMigrations(req.Context()) }},
+		}
+
+		admin := checkAdmin(bearerToken(req)) == nil
+
+		if req.URL.Query().Get("backends") == "true" {
+			if !admin {
+				writeError(w, authFailed(errInvalidAdminToken))
+				return
+			}
+
+			checks = append(checks, check{"mail", true, mail.Ping})
+
+			if config.StripeKey != "" {
+				checks = append(checks, check{"billing", true, func() error {
+					return stripe.GetBackend(stripe.APIBackend).Call("GET", "/balance", config.StripeKey, nil, nil, &struct{}{})
+				}})
+			}
+		}
+
+		status, result := runChecks(checks, admin)
+		writeHealth(w, status, result)
+	}
+}
+
+func checkMigrations(ctx context.Context) error {
+	pending, err := data.MigrationsPendingContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if pending > 0 {
+		return fmt.Errorf("%d migrations pending", pending)
+	}
+
+	return nil
+}
+
+// runChecks runs all checks and returns the response status with the result
+// of every check, errors are only included when detailed
+func runChecks(checks []check, detailed bool) (int, APIResponseStructHealth) {
+	status := http.StatusOK
+	result := APIResponseStructHealth{"ok", map[string]APIResponseStructCheck{}}
+
+	for _, c := range checks {
+		start := time.Now()
+		err := c.Run()
+		item := APIResponseStructCheck{"ok", float64(time.Since(start)) / float64(time.Millisecond), ""}
+
+		if err != nil {
+			item.Status = "fail"
+
+			if detailed {
+				item.Error = err.Error()
+			}
+
+			if !c.Optional {
+				status = http.StatusServiceUnavailable
+				result.Status = "fail"
+			}
+		}
+
+		result.Checks[c.Name] = item
+	}
+
+	return status, result
+}
+
+func writeHealth(w http.ResponseWriter, status int, result APIResponseStructHealth) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,79 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealth(t *testing.T) {
+	res := httptest.NewRecorder()
+	Health(res, httptest.NewRequest("GET", "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "{\"status\":\"ok\"}\n", res.Body.String())
+}
+
+func TestRunChecks(t *testing.T) {
+	ok := func() error { return nil }
+	failed := func() error { return errors.New("unreachable") }
+
+	status, result := runChecks([]check{
+		{"database", false, ok},
+		{"mail", true, failed},
+	}, true)
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "ok", result.Status)
+	assert.Equal(t, "ok", result.Checks["database"].Status)
+	assert.Equal(t, "fail", result.Checks["mail"].Status)
+	assert.Equal(t, "unreachable", result.Checks["mail"].Error)
+
+	status, result = runChecks([]check{
+		{"database", false, failed},
+	}, false)
+
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, "fail", result.Status)
+	assert.Equal(t, "", result.Checks["database"].Error)
+
+	res := httptest.NewRecorder()
+	writeHealth(res, status, result)
+
+	var body map[string]interface{}
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &body))
+	assert.Contains(t, body["checks"].(map[string]interface{})["database"], "latency_ms")
+}
+
+func TestReadyBackends(t *testing.T) {
+	conf = Configuration{AdminToken: "secret"}
+	defer func() { conf = Configuration{} }()
+
+	res := httptest.NewRecorder()
+	Ready(conf)(res, httptest.NewRequest("GET", "/readyz?backends=true", nil))
+
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+	assert.Contains(t, res.Body.String(), `"code":"invalid_admin_token"`)
+}
@@ -0,0 +1,130 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/clinotes/server/data"
+	"github.com/clinotes/server/metrics"
+	"github.com/gorilla/mux"
+)
+
+var (
+	metricRequests = metrics.NewCounter(
+		"clinotes_http_requests_total", "Requests handled by route, method and status.",
+		"route", "method", "status",
+	)
+	metricRequestDuration = metrics.NewHistogram(
+		"clinotes_http_request_duration_seconds", "Time to handle requests by route.",
+		metrics.DefaultBuckets, "route", "method",
+	)
+	metricAuthFailures = metrics.NewCounter(
+		"clinotes_auth_failures_total", "Rejected authentication attempts by reason.",
+		"reason",
+	)
+	metricTokenVerify = metrics.NewHistogram(
+		"clinotes_token_verify_duration_seconds", "Time to look up and verify access tokens.",
+		[]float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1},
+	)
+)
+
+// Metrics serves the collected metrics, scrapers authenticate with the admin
+// token as bearer token when one is configured
+func Metrics(w http.ResponseWriter, r *http.Request) {
+	if conf.AdminToken != "" {
+		if err := checkAdmin(bearerToken(r)); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+
+	metrics.Handler(w, r)
+}
+
+// statusWriter remembers the status written to a response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the connection
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// measure counts the request to route and records its duration
+func measure(route Route, w http.ResponseWriter, r *http.Request, serve func(http.ResponseWriter)) {
+	url := route.URL
+	if current := mux.CurrentRoute(r); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			url = template
+		}
+	}
+
+	requestFrom(r).Route = url
+
+	start := time.Now()
+	recorder := &statusWriter{w, http.StatusOK}
+
+	// Aborted streams are counted too
+	defer func() {
+		metricRequests.Inc(url, route.Method, strconv.Itoa(recorder.status))
+		metricRequestDuration.Observe(time.Since(start).Seconds(), url, route.Method)
+	}()
+
+	serve(recorder)
+}
+
+// verifyToken looks up the token of account matching raw, records the time
+// it takes and logs the account of req once verified. Tokens not matching
+// fail with errInvalidToken, failed lookups with an internal error
+func verifyToken(req *http.Request, account *data.Account, raw string, tokenType int) (*data.Token, error) {
+	start := time.Now()
+	token, err := account.GetTokenContext(req.Context(), raw, tokenType)
+	metricTokenVerify.Observe(time.Since(start).Seconds())
+
+	if err == data.ErrTokenNotFound {
+		return nil, errInvalidToken
+	}
+
+	if err != nil {
+		return nil, errInternal("Unable to verify token").WithCause(err)
+	}
+
+	requestFrom(req).Account = account.ID
+	return token, nil
+}
+
+// authFailed counts a rejected authentication attempt by the code of err,
+// internal errors are no rejection and not counted
+func authFailed(err error) error {
+	if apiErr := toError(err); apiErr.Status < http.StatusInternalServerError {
+		metricAuthFailures.Inc(apiErr.Code)
+	}
+
+	return err
+}
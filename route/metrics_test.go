@@ -0,0 +1,53 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	route := Route{"GET", "/teapot", nil, nil, func(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+		return nil, authFailed(errInvalidToken)
+	}, nil}
+
+	route.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/teapot", nil))
+	authFailed(errInternal("Unable to get account"))
+
+	conf = Configuration{AdminToken: "secret"}
+	defer func() { conf = Configuration{} }()
+
+	res := httptest.NewRecorder()
+	Metrics(res, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+
+	res = httptest.NewRecorder()
+	Metrics(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Contains(t, res.Body.String(), `clinotes_http_requests_total{route="/teapot",method="GET",status="401"} 1`)
+	assert.Contains(t, res.Body.String(), `clinotes_auth_failures_total{reason="invalid_token"} 1`)
+	assert.NotContains(t, res.Body.String(), `reason="internal_error"`)
+}
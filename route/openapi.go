@@ -0,0 +1,257 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// formRequest is implemented by Request types sent as multipart form
+type formRequest interface {
+	form()
+}
+
+var (
+	pathParameter = regexp.MustCompile(`{([^}]+)}`)
+	timeType      = reflect.TypeOf(time.Time{})
+	streamType    = reflect.TypeOf(Stream(nil))
+)
+
+// openAPI collects named schemas while describing routes
+type openAPI struct {
+	schemas map[string]interface{}
+}
+
+// OpenAPI describes all routes of versions as OpenAPI 3 document
+func OpenAPI(versions []Version, release string) map[string]interface{} {
+	doc := openAPI{map[string]interface{}{}}
+	doc.schemas["Error"] = doc.schema(reflect.TypeOf(apiResponseError{}))
+
+	paths := map[string]map[string]interface{}{}
+	for _, version := range versions {
+		for _, r := range version.Routes {
+			path := version.Prefix + r.URL
+			if paths[path] == nil {
+				paths[path] = map[string]interface{}{}
+			}
+
+			paths[path][strings.ToLower(r.Method)] = doc.operation(r, path)
+		}
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "CLI Notes API",
+			"version": release,
+		},
+		"paths": paths,
+		// Routes below /v1 use basic auth, all others pass credentials in
+		// the request body
+		"security": []map[string][]string{
+			{"basicAuth": {}},
+			{},
+		},
+		"components": map[string]interface{}{
+			"schemas": doc.schemas,
+			"securitySchemes": map[string]interface{}{
+				"basicAuth": map[string]string{"type": "http", "scheme": "basic"},
+			},
+		},
+	}
+}
+
+// OpenAPIHandler serves the OpenAPI document of versions
+func OpenAPIHandler(versions []Version, release string) http.HandlerFunc {
+	text, _ := json.Marshal(OpenAPI(versions, release))
+
+	return func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("Content-Type", "application/json; charset=utf-8")
+		res.Write(text)
+	}
+}
+
+func (doc openAPI) operation(r Route, path string) map[string]interface{} {
+	operation := map[string]interface{}{
+		"operationId": strings.ToLower(r.Method) + strings.Replace(strings.Replace(strings.Replace(path, "/", "_", -1), "{", "", -1), "}", "", -1),
+		"responses": map[string]interface{}{
+			"200": map[string]interface{}{
+				"description": "Success",
+				"content":     doc.response(r.Response),
+			},
+			"default": map[string]interface{}{
+				"description": "Error",
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{
+						"schema": ref("Error"),
+					},
+				},
+			},
+		},
+	}
+
+	if r.Deprecation != nil {
+		operation["deprecated"] = true
+		operation["description"] = "Deprecated, use " + r.Deprecation.Successor + " instead"
+	}
+
+	var parameters []map[string]interface{}
+	for _, match := range pathParameter.FindAllStringSubmatch(path, -1) {
+		parameters = append(parameters, map[string]interface{}{
+			"name":     match[1],
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]string{"type": "integer"},
+		})
+	}
+
+	if parameters != nil {
+		operation["parameters"] = parameters
+	}
+
+	if r.Request != nil {
+		contentType := "application/json"
+		if _, ok := r.Request.(formRequest); ok {
+			contentType = "multipart/form-data"
+		}
+
+		operation["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				contentType: map[string]interface{}{
+					"schema": doc.schema(reflect.TypeOf(r.Request)),
+				},
+			},
+		}
+	}
+
+	return operation
+}
+
+// response describes the body written by Handler.ServeHTTP for data
+func (doc openAPI) response(data interface{}) map[string]interface{} {
+	if data != nil && reflect.TypeOf(data) == streamType {
+		return map[string]interface{}{
+			"application/octet-stream": map[string]interface{}{
+				"schema": map[string]string{"type": "string", "format": "binary"},
+			},
+		}
+	}
+
+	envelope := doc.schema(reflect.TypeOf(apiResponseSuccess{}))
+	if data != nil {
+		envelope = doc.schema(reflect.TypeOf(apiResponseData{}))
+		envelope["properties"].(map[string]interface{})["Data"] = doc.schema(reflect.TypeOf(data))
+	}
+
+	return map[string]interface{}{
+		"application/json": map[string]interface{}{
+			"schema": envelope,
+		},
+	}
+}
+
+// schema describes t as JSON schema, exported structs are added to the
+// components of the document and referenced
+func (doc openAPI) schema(t reflect.Type) map[string]interface{} {
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		schema := doc.schema(t.Elem())
+		if _, ok := schema["$ref"]; ok {
+			return schema
+		}
+
+		schema["nullable"] = true
+		return schema
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "binary"}
+		}
+
+		return map[string]interface{}{"type": "array", "items": doc.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": doc.schema(t.Elem())}
+	case reflect.Struct:
+		return doc.object(t)
+	}
+
+	return map[string]interface{}{}
+}
+
+func (doc openAPI) object(t reflect.Type) map[string]interface{} {
+	name := t.Name()
+	exported := name != "" && name[0] >= 'A' && name[0] <= 'Z'
+
+	if _, ok := doc.schemas[name]; ok && exported {
+		return ref(name)
+	}
+
+	// Reserve the name first, so recursive types end up as reference
+	if exported {
+		doc.schemas[name] = map[string]interface{}{}
+	}
+
+	properties := map[string]interface{}{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := field.Name
+		if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+
+		properties[name] = doc.schema(field.Type)
+	}
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if !exported {
+		return schema
+	}
+
+	doc.schemas[name] = schema
+	return ref(name)
+}
+
+func ref(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
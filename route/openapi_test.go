@@ -0,0 +1,129 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenAPI(t *testing.T) {
+	versions := Versions(Configuration{})
+
+	res := httptest.NewRecorder()
+	OpenAPIHandler(versions, "test")(res, httptest.NewRequest("GET", "/openapi.json", nil))
+
+	var doc map[string]interface{}
+	if !assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &doc)) {
+		return
+	}
+
+	assert.Equal(t, "3.0.3", doc["openapi"])
+	assert.Equal(t, "test", doc["info"].(map[string]interface{})["version"])
+
+	paths := doc["paths"].(map[string]interface{})
+	schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+
+	// Every route is described with its request and path parameters
+	for _, version := range versions {
+		for _, r := range version.Routes {
+			path := version.Prefix + r.URL
+
+			item, ok := paths[path].(map[string]interface{})
+			if !assert.True(t, ok, path) {
+				continue
+			}
+
+			operation, ok := item[strings.ToLower(r.Method)].(map[string]interface{})
+			if !assert.True(t, ok, r.Method+" "+path) {
+				continue
+			}
+
+			assert.NotNil(t, operation["responses"].(map[string]interface{})["200"], path)
+			assert.Equal(t, r.Request != nil, operation["requestBody"] != nil, path)
+
+			if r.Method != "GET" && r.Method != "DELETE" {
+				assert.NotNil(t, r.Request, r.Method+" "+path)
+			}
+
+			parameters, _ := operation["parameters"].([]interface{})
+			assert.Equal(t, strings.Count(path, "{"), len(parameters), path)
+		}
+	}
+
+	// References must point to described schemas
+	for _, name := range collectRefs(doc) {
+		_, ok := schemas[strings.TrimPrefix(name, "#/components/schemas/")]
+		assert.True(t, ok, name)
+	}
+}
+
+func TestOpenAPISchema(t *testing.T) {
+	doc := openAPI{map[string]interface{}{}}
+
+	schema := doc.schema(reflect.TypeOf([]APIResponseStructV1Note{}))
+	assert.Equal(t, "array", schema["type"])
+	assert.Equal(t, "#/components/schemas/APIResponseStructV1Note", schema["items"].(map[string]interface{})["$ref"])
+
+	note := doc.schemas["APIResponseStructV1Note"].(map[string]interface{})
+	properties := note["properties"].(map[string]interface{})
+
+	assert.Equal(t, "integer", properties["id"].(map[string]interface{})["type"])
+	assert.Equal(t, "date-time", properties["created"].(map[string]interface{})["format"])
+	assert.Nil(t, properties["ID"])
+
+	share := doc.schema(reflect.TypeOf(APIResponseStructShare{}))
+	assert.NotNil(t, share["$ref"])
+}
+
+func TestOpenAPIForm(t *testing.T) {
+	doc := OpenAPI([]Version{{"", []Route{APIRouteImport}}}, "test")
+
+	operation := doc["paths"].(map[string]map[string]interface{})["/import"]["post"].(map[string]interface{})
+	content := operation["requestBody"].(map[string]interface{})["content"].(map[string]interface{})
+
+	assert.NotNil(t, content["multipart/form-data"])
+	assert.Nil(t, content["application/json"])
+}
+
+func collectRefs(value interface{}) []string {
+	var list []string
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if name, ok := item.(string); ok && key == "$ref" {
+				list = append(list, name)
+			}
+
+			list = append(list, collectRefs(item)...)
+		}
+	case []interface{}:
+		for _, item := range v {
+			list = append(list, collectRefs(item)...)
+		}
+	}
+
+	return list
+}
@@ -0,0 +1,148 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/clinotes/server/logger"
+	"github.com/gorilla/mux"
+)
+
+// requestIDHeader carries the id of a request, it is taken from the client
+// or proxy when valid and generated otherwise
+const requestIDHeader = "X-Request-ID"
+
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// requestInfo collects what is logged about a request while it is handled
+type requestInfo struct {
+	ID      string
+	Route   string
+	Account int
+	Err     error
+}
+
+type contextKey int
+
+const requestInfoKey contextKey = iota
+
+// RequestID assigns every request an id, returns it in the X-Request-ID
+// header and logs the request once it has been handled
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info := &requestInfo{ID: r.Header.Get(requestIDHeader), Route: r.URL.Path}
+		if !requestIDPattern.MatchString(info.ID) {
+			info.ID = newRequestID()
+		}
+
+		w.Header().Set(requestIDHeader, info.ID)
+
+		start := time.Now()
+		recorder := &statusWriter{w, http.StatusOK}
+
+		// Aborted requests are logged before the panic reaches the server
+		defer func() {
+			aborted := recover()
+			logRequest(r, info, recorder.status, start, aborted != nil)
+
+			if aborted != nil {
+				panic(aborted)
+			}
+		}()
+
+		next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), requestInfoKey, info)))
+	})
+}
+
+// logRequest writes the log line of a handled request
+func logRequest(r *http.Request, info *requestInfo, status int, start time.Time, aborted bool) {
+	fields := logger.Fields{
+		"request_id": info.ID,
+		"method":     r.Method,
+		"route":      info.Route,
+		"status":     status,
+		"latency_ms": float64(time.Since(start)) / float64(time.Millisecond),
+	}
+
+	if info.Account != 0 {
+		fields["account"] = info.Account
+	}
+
+	if info.Err != nil {
+		fields["error"] = info.Err
+	}
+
+	if aborted {
+		fields["aborted"] = true
+	}
+
+	if aborted || status >= http.StatusInternalServerError {
+		logger.Error("request failed", fields)
+	} else {
+		logger.Info("request", fields)
+	}
+}
+
+// Deadline cancels the context of requests, and with it their queries,
+// after timeout. Routes of router transferring whole accounts get transfer
+// instead, which also replaces the read and write timeouts of the server
+func Deadline(timeout time.Duration, transfer time.Duration, router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := timeout
+
+		var match mux.RouteMatch
+		if router.Match(r, &match) {
+			if route, ok := match.Handler.(Route); ok && route.transfers() {
+				limit = transfer
+
+				controller := http.NewResponseController(w)
+				controller.SetReadDeadline(time.Now().Add(transfer))
+				controller.SetWriteDeadline(time.Now().Add(transfer))
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), limit)
+		defer cancel()
+
+		router.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requestFrom returns the requestInfo of r, requests not passed through
+// RequestID get one which is not logged
+func requestFrom(r *http.Request) *requestInfo {
+	if info, ok := r.Context().Value(requestInfoKey).(*requestInfo); ok {
+		return info
+	}
+
+	return &requestInfo{}
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	return hex.EncodeToString(id)
+}
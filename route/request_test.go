@@ -0,0 +1,123 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/clinotes/server/logger"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	var out bytes.Buffer
+	logger.SetOutput(&out)
+	defer logger.SetOutput(os.Stdout)
+
+	route := Route{"GET", "/broken", nil, nil, func(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+		requestFrom(r).Account = 7
+		return nil, errInternal("Unable to store note").WithCause(errors.New("pq: connection refused"))
+	}, nil}
+	handler := RequestID(route)
+
+	req := httptest.NewRequest("GET", "/broken", nil)
+	req.Header.Set(requestIDHeader, "abc-123")
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+
+	assert.Equal(t, "abc-123", res.Header().Get(requestIDHeader))
+	assert.Equal(t, http.StatusInternalServerError, res.Code)
+	assert.NotContains(t, res.Body.String(), "pq:")
+	assert.Contains(t, out.String(), "level=error")
+	assert.Contains(t, out.String(), "request_id=abc-123")
+	assert.Contains(t, out.String(), "account=7")
+	assert.Contains(t, out.String(), `error="pq: connection refused"`)
+
+	req = httptest.NewRequest("GET", "/broken", nil)
+	req.Header.Set(requestIDHeader, "not valid")
+
+	res = httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	assert.Len(t, res.Header().Get(requestIDHeader), 32)
+}
+
+func TestDeadline(t *testing.T) {
+	route := Route{"GET", "/slow", nil, nil, func(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+		<-r.Context().Done()
+		return nil, errInternal("Unable to get notes").WithCause(r.Context().Err())
+	}, nil}
+
+	export := Route{"GET", "/export", nil, Stream(nil), func(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+		deadline, _ := r.Context().Deadline()
+		return Stream(func(w http.ResponseWriter) error {
+			_, err := w.Write([]byte(time.Until(deadline).Round(time.Minute).String()))
+			return err
+		}), nil
+	}, nil}
+
+	router := mux.NewRouter()
+	router.Handle("/slow", route)
+	router.Handle("/export", export)
+	handler := Deadline(10*time.Millisecond, 10*time.Minute, router)
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest("GET", "/slow", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, res.Code)
+	assert.Contains(t, res.Body.String(), `"code":"request_timeout"`)
+
+	res = httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest("GET", "/export", nil))
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "10m0s", res.Body.String())
+}
+
+func TestStreamAborted(t *testing.T) {
+	var out bytes.Buffer
+	logger.SetOutput(&out)
+	defer logger.SetOutput(os.Stdout)
+
+	route := Route{"POST", "/export", nil, Stream(nil), func(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+		return Stream(func(w http.ResponseWriter) error {
+			w.Write([]byte("partial"))
+			return errors.New("pq: connection reset")
+		}), nil
+	}, nil}
+
+	func() {
+		defer func() {
+			assert.Equal(t, http.ErrAbortHandler, recover())
+		}()
+
+		RequestID(route).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/export", nil))
+	}()
+
+	assert.Contains(t, out.String(), "level=error")
+	assert.Contains(t, out.String(), "aborted=true")
+	assert.Contains(t, out.String(), `error="pq: connection reset"`)
+}
@@ -19,16 +19,27 @@
 package route
 
 import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/keighl/postmark"
+	"github.com/clinotes/server/data"
+	"github.com/clinotes/server/mail"
+	"github.com/gorilla/mux"
 )
 
 // Handler is
 type Handler func(http.ResponseWriter, *http.Request) (interface{}, error)
 
+// Stream is returned by a Handler writing the response body on its own
+type Stream func(http.ResponseWriter) error
+
 // APIResponseData is
 type apiResponseData struct {
 	Data  interface{}
@@ -44,45 +55,106 @@ type apiResponseSuccess struct {
 
 // APIResponseError is an API error
 type apiResponseError struct {
-	Error bool   `json:"error"`
-	Text  string `json:"text"`
+	Error   bool              `json:"error"`
+	Text    string            `json:"text"`
+	Code    string            `json:"code"`
+	Status  int               `json:"status"`
+	Details map[string]string `json:"details,omitempty"`
 }
 
-// Route is a route
+// Route is a route, Request and Response are zero values of the types
+// exchanged with the Handler and used to describe the API
 type Route struct {
-	URL     string
-	Handler Handler
+	Method      string
+	URL         string
+	Request     interface{}
+	Response    interface{}
+	Handler     Handler
+	Deprecation *Deprecation
 }
 
-func (handler Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// Set JSON response header
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+// Version is a set of routes mounted below a common path prefix, so several
+// versions of the API can be served side by side
+type Version struct {
+	Prefix string
+	Routes []Route
+}
 
+func (handler Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Prepare response object
 	var response interface{}
 
 	// Check for error in route handler
 	data, err := handler(w, r)
+
+	// Streams write their own response, once it has started a failure can
+	// only be signaled by aborting the transfer
+	if stream, ok := data.(Stream); ok && err == nil {
+		if err = stream(w); err != nil {
+			requestFrom(r).Err = err
+			panic(http.ErrAbortHandler)
+		}
+
+		return
+	}
+
+	// Queries canceled by the request deadline fail with generic errors
+	if err != nil && r.Context().Err() == context.DeadlineExceeded {
+		err = errTimeout.WithCause(toError(err).cause)
+	}
+
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		response = apiResponseError{true, err.Error()}
+		requestFrom(r).Err = toError(err).cause
+		writeError(w, err)
+		return
+	}
+
+	if data != nil {
+		response = apiResponseData{data, false, !false}
 	} else {
-		if data != nil {
-			response = apiResponseData{data, false, !false}
-		} else {
-			response = apiResponseSuccess{false, true}
-		}
+		response = apiResponseSuccess{false, true}
 	}
 
 	// Write response
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	text, _ := json.Marshal(response)
 	w.Write([]byte(string(text)))
 }
 
+// transfers checks if route streams its response or receives an upload,
+// which may take longer than other requests
+func (route Route) transfers() bool {
+	_, form := route.Request.(formRequest)
+	_, stream := route.Response.(Stream)
+
+	return form || stream
+}
+
+// ServeHTTP announces the deprecation of Route before handling the request
+// and records request metrics
+func (route Route) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if route.Deprecation != nil {
+		route.Deprecation.announce(w)
+	}
+
+	measure(route, w, r, func(w http.ResponseWriter) {
+		route.Handler.ServeHTTP(w, r)
+	})
+}
+
+// writeError writes err as JSON response with its status
+func writeError(w http.ResponseWriter, err error) {
+	apiErr := toError(err)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(apiErr.Status)
+
+	text, _ := json.Marshal(apiResponseError{true, apiErr.Text, apiErr.Code, apiErr.Status, apiErr.Details})
+	w.Write(text)
+}
+
 var (
-	conf      Configuration
-	pmark     *postmark.Client
-	templates map[string]int64
+	conf Configuration
 )
 
 // Configuration stores need variables
@@ -90,29 +162,95 @@ type Configuration struct {
 	TemplateWelcome int64
 	TemplateConfirm int64
 	TemplateToken   int64
+	TemplateInvite  int64
+	TemplateDelete  int64
+	TemplateGoodbye int64
+	TemplateEmail   int64
+	TemplateNotice  int64
+
+	TrialPeriod time.Duration
+	StripeKey   string
 
-	PostmarkToken   string
-	PostmarkFrom    string
-	PostmarkReplyTo string
+	// ClientMinimum is the oldest client version accepted and ClientLatest
+	// the current one, older clients are asked to upgrade
+	ClientMinimum string
+	ClientLatest  string
+
+	// AdminToken grants access to the admin routes, which are disabled
+	// when empty
+	AdminToken string
 }
 
-// Routes returns available routes
+// Versions returns all versions of the API
+func Versions(config Configuration) []Version {
+	return []Version{
+		{"", Routes(config)},
+		{"/v1", RoutesV1(config)},
+	}
+}
+
+// Routes returns available routes of the legacy API
 func Routes(config Configuration) []Route {
 	conf = config
-	pmark = postmark.NewClient(config.PostmarkToken, "")
 
 	return []Route{
-		APIRouteAdd,
+		deprecated(APIRouteAdd, "/v1/notes"),
 		APIRouteAuth,
-		APIRouteAccountCreate,
-		APIRouteAccountVerify,
-		APIRouteTokenCreate,
+		deprecated(APIRouteAccountCreate, "/v1/accounts"),
+		deprecated(APIRouteAccountVerify, "/v1/account/verification"),
+		deprecated(APIRouteAccountVerifyResend, "/v1/account/verification/mail"),
+		APIRouteAccountDelete,
+		APIRouteAccountExport,
+		APIRouteAccountEmail,
+		deprecated(APIRouteTokenCreate, "/v1/tokens"),
 		APIRouteSubscribe,
-		APIRouteAccount,
-		APIRouteNotes,
+		deprecated(APIRouteAccount, "/v1/account"),
+		deprecated(APIRouteNotes, "/v1/notes"),
+		APIRouteBillingInvoices,
+		deprecated(APIRouteOrganizations, "/v1/organizations"),
+		APIRouteOrganizationCreate,
+		APIRouteOrganizationInvite,
+		APIRouteOrganizationJoin,
+		APIRouteOrganizationMembers,
+		APIRouteOrganizationRole,
+		APIRouteOrganizationRemove,
+		APIRouteShareCreate,
+		APIRouteShares,
+		APIRouteShareRevoke,
+		deprecated(APIRouteNotesHistory, "/v1/notes/{id}/revisions"),
+		APIRouteNotesRestore,
+		APIRouteExport,
+		APIRouteImport,
+		APIRouteAdminOutbox,
+		APIRouteAdminOutboxRetry,
 	}
 }
 
+// RoutesV1 returns available routes of the REST API
+func RoutesV1(config Configuration) []Route {
+	conf = config
+
+	return []Route{
+		alias("POST", "/accounts", APIRouteAccountCreate),
+		alias("POST", "/account/verification", APIRouteAccountVerify),
+		alias("POST", "/account/verification/mail", APIRouteAccountVerifyResend),
+		alias("POST", "/tokens", APIRouteTokenCreate),
+		APIRouteV1Account,
+		APIRouteV1Notes,
+		APIRouteV1NoteCreate,
+		APIRouteV1Note,
+		APIRouteV1NoteUpdate,
+		APIRouteV1NoteDelete,
+		APIRouteV1NoteRevisions,
+		APIRouteV1Organizations,
+	}
+}
+
+// alias serves route at another method and URL
+func alias(method string, url string, route Route) Route {
+	return Route{method, url, route.Request, route.Response, route.Handler, nil}
+}
+
 func checkJSONBody(req *http.Request, res http.ResponseWriter, data interface{}) error {
 	// Decode body
 	decoder := json.NewDecoder(req.Body)
@@ -121,20 +259,138 @@ func checkJSONBody(req *http.Request, res http.ResponseWriter, data interface{})
 
 	// Respond with BadRequest status
 	if err != nil {
-		return errors.New("Invalid JSON data")
+		return errInvalidJSON
 	}
 
 	return nil
 }
 
-func sendTokenWithTemplate(to string, token string, template int64) (postmark.EmailResponse, error) {
-	return pmark.SendTemplatedEmail(postmark.TemplatedEmail{
-		TemplateId: template,
-		TemplateModel: map[string]interface{}{
-			"token": token,
-		},
-		From:    conf.PostmarkFrom,
-		To:      to,
-		ReplyTo: conf.PostmarkReplyTo,
-	})
+// queueTokenWithTemplate writes a mail containing token to the outbox
+func queueTokenWithTemplate(to string, token string, template int64) error {
+	return mail.QueueToken(to, token, template)
+}
+
+// roles maps role names used in the API to data roles
+var roles = map[string]int{
+	"owner":  data.RoleOwner,
+	"editor": data.RoleEditor,
+	"viewer": data.RoleViewer,
+}
+
+func roleName(role int) string {
+	for name, value := range roles {
+		if value == role {
+			return name
+		}
+	}
+
+	return ""
+}
+
+func checkAccount(req *http.Request, address string, token string) (*data.Account, error) {
+	account, _, err := checkToken(req, address, token)
+
+	return account, err
+}
+
+// accountByAddress retrieves the account of address, only a missing account
+// is reported as unknown
+func accountByAddress(req *http.Request, address string) (*data.Account, error) {
+	account, err := data.AccountByAddressContext(req.Context(), address)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errUnknownAccount
+	}
+
+	if err != nil {
+		return nil, errInternal("Unable to get account").WithCause(err)
+	}
+
+	return account, nil
+}
+
+func checkToken(req *http.Request, address string, token string) (*data.Account, *data.Token, error) {
+	// Get account
+	account, err := accountByAddress(req, address)
+	if err != nil {
+		return nil, nil, authFailed(err)
+	}
+
+	if !account.Verified {
+		return nil, nil, authFailed(errNotVerified)
+	}
+
+	// Check if account has requested token
+	accessToken, err := verifyToken(req, account, token, data.TokenTypeAccess)
+	if err != nil {
+		return nil, nil, authFailed(err)
+	}
+
+	return account, accessToken, nil
+}
+
+// checkBasicAuth authenticates requests of the REST API, which pass the
+// account address as user name and an access token as password
+func checkBasicAuth(req *http.Request) (*data.Account, *data.Token, error) {
+	address, token, ok := req.BasicAuth()
+	if !ok {
+		return nil, nil, authFailed(newError(http.StatusUnauthorized, "missing_credentials", "Missing credentials"))
+	}
+
+	return checkToken(req, address, token)
+}
+
+// pathID reads the numeric id of a resource from the request path
+func pathID(req *http.Request) int {
+	id, _ := strconv.Atoi(mux.Vars(req)["id"])
+
+	return id
+}
+
+func checkMember(req *http.Request, account *data.Account, organization int, role int) (*data.Member, error) {
+	member, err := data.MemberByOrganizationAndAccountContext(req.Context(), organization, account.ID)
+	if err != nil {
+		return nil, errUnknownOrganization
+	}
+
+	if !member.Can(role) {
+		return nil, errNotAllowed
+	}
+
+	return member, nil
+}
+
+func checkNote(req *http.Request, account *data.Account, id int, role int) (*data.Note, error) {
+	note, err := data.NoteByIDContext(req.Context(), id)
+	if err != nil {
+		return nil, errUnknownNote
+	}
+
+	// Notes of an organization are checked against the member role
+	if note.Organization != 0 {
+		if _, err = checkMember(req, account, note.Organization, role); err != nil {
+			return nil, err
+		}
+
+		return note, nil
+	}
+
+	if note.Account != account.ID {
+		return nil, errUnknownNote
+	}
+
+	return note, nil
+}
+
+// bearerToken returns the token of the Authorization header of req
+func bearerToken(req *http.Request) string {
+	return strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+}
+
+// checkAdmin validates the admin token
+func checkAdmin(token string) error {
+	if conf.AdminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(conf.AdminToken)) != 1 {
+		return authFailed(errInvalidAdminToken)
+	}
+
+	return nil
 }
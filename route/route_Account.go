@@ -19,7 +19,6 @@
 package route
 
 import (
-	"errors"
 	"net/http"
 	"time"
 
@@ -41,7 +40,10 @@ type APIResponseStructAccount struct {
 
 // APIRouteAccount is
 var APIRouteAccount = Route{
+	"POST",
 	"/account",
+	APIRequestStructMe{},
+	APIResponseStructAccount{},
 	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
 		// Parse JSON request
 		var reqData APIRequestStructMe
@@ -50,27 +52,28 @@ var APIRouteAccount = Route{
 		}
 
 		// Get account
-		account, err := data.AccountByAddress(reqData.Address)
+		account, err := accountByAddress(req, reqData.Address)
 		if err != nil {
-			return nil, errors.New("Unknown account address")
+			return nil, authFailed(err)
 		}
 
 		// Check if account has requested token
-		_, err = account.GetToken(reqData.Token, data.TokenTypeAccess)
+		_, err = verifyToken(req, account, reqData.Token, data.TokenTypeAccess)
 		if err != nil {
-			return nil, errors.New("Unable to use provided token")
+			return nil, authFailed(err)
 		}
 
 		// Verify account
-		account, err = account.Verify()
+		account, err = account.VerifyContext(req.Context())
 		if err != nil {
-			return nil, errors.New("Unable to use provided token")
+			return nil, errInternal("Unable to verify account").WithCause(err)
 		}
 
 		return APIResponseStructAccount{
 			account.Address,
 			account.Created,
-			account.HasSubscription(),
+			account.HasSubscriptionContext(req.Context()),
 		}, nil
 	},
+	nil,
 }
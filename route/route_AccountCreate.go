@@ -19,10 +19,12 @@
 package route
 
 import (
-	"errors"
 	"net/http"
+	"time"
 
 	"github.com/clinotes/server/data"
+	"github.com/clinotes/server/mail"
+	"github.com/jmoiron/sqlx"
 )
 
 // APIRequestStructCreateUser is
@@ -32,44 +34,58 @@ type APIRequestStructCreateUser struct {
 
 // APIRouteAccountCreate is
 var APIRouteAccountCreate = Route{
+	"POST",
 	"/account/create",
+	APIRequestStructCreateUser{},
+	nil,
 	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
 		var reqData APIRequestStructCreateUser
 		if err := checkJSONBody(req, res, &reqData); err != nil {
 			return nil, err
 		}
 
-		account := data.AccountNew(reqData.Address)
-		account, err := account.Store()
-
-		// If account cannot be created, fail
+		address, err := data.ParseAddress(reqData.Address)
 		if err != nil {
-			return nil, errors.New("Unable to create account")
+			return nil, errAddress("address", err)
 		}
 
-		token := data.TokenNew(account.ID, data.TokenTypeMaintenace)
-		tokenRaw := token.Raw()
-		token, err = token.Store()
+		// Account, token, trial and welcome mail are created together or not
+		// at all, the mail is delivered by the outbox once committed
+		err = data.TransactionContext(req.Context(), func(tx *sqlx.Tx) error {
+			account, err := data.AccountNew(address).StoreTx(tx)
+			if err != nil {
+				return err
+			}
 
-		// If token cannot be created, fail and remove user
-		if err != nil {
-			account.Remove()
-			return nil, errors.New("Unable to create account")
-		}
+			token := data.TokenNew(account.ID, data.TokenTypeMaintenace)
+			if _, err = token.StoreTx(tx); err != nil {
+				return err
+			}
 
-		// Send confirmation mail using Postmark
-		_, err = sendTokenWithTemplate(account.Address, tokenRaw, conf.TemplateWelcome)
-		if err != nil {
-			return nil, errors.New("Unable to send welcome mail")
+			// Start free trial of the paid plan if configured
+			if conf.TrialPeriod > 0 {
+				trial := data.SubscriptionTrialNew(account.ID, time.Now().Add(conf.TrialPeriod))
+				if _, err = trial.StoreTx(tx); err != nil {
+					return err
+				}
+			}
+
+			return mail.QueueTokenTx(tx, account.Address, token.Raw(), conf.TemplateWelcome)
+		})
+
+		// If account cannot be created, fail
+		if data.IsDuplicate(err) {
+			return nil, errAddressInUse
 		}
 
-		// If mail cannot be sent, fail and remove user
 		if err != nil {
-			account.Remove()
-			return nil, errors.New("Unable to create account")
+			return nil, errInternal("Unable to create account").WithCause(err)
 		}
 
+		mail.Wake()
+
 		// Done!
 		return nil, nil
 	},
+	nil,
 }
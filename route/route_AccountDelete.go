@@ -0,0 +1,125 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/clinotes/server/data"
+	"github.com/clinotes/server/mail"
+	stripe "github.com/stripe/stripe-go"
+	stripeSub "github.com/stripe/stripe-go/sub"
+)
+
+// deleteTokenLifetime limits how long a mailed deletion token can be used
+const deleteTokenLifetime = time.Hour
+
+// APIRequestStructDeleteAccount is
+type APIRequestStructDeleteAccount struct {
+	Address      string `json:"address"`
+	Token        string `json:"token"`
+	Confirmation string `json:"confirmation"`
+}
+
+// APIRouteAccountDelete is
+var APIRouteAccountDelete = Route{
+	"POST",
+	"/account/delete",
+	APIRequestStructDeleteAccount{},
+	nil,
+	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
+		// Deletion is confirmed by mail and disabled without its template
+		if conf.TemplateDelete == 0 {
+			return nil, errNotEnabled
+		}
+
+		// Parse JSON request
+		var reqData APIRequestStructDeleteAccount
+		if err := checkJSONBody(req, res, &reqData); err != nil {
+			return nil, err
+		}
+
+		account, err := checkAccount(req, reqData.Address, reqData.Token)
+		if err != nil {
+			return nil, err
+		}
+
+		// Without confirmation send a deletion token to confirm deletion
+		if reqData.Confirmation == "" {
+			token := data.TokenNew(account.ID, data.TokenTypeDeletion)
+			tokenRaw := token.Raw()
+			token, err = token.StoreContext(req.Context())
+
+			if err != nil {
+				return nil, errInternal("Unable to create token for account").WithCause(err)
+			}
+
+			err = queueTokenWithTemplate(account.Address, tokenRaw, conf.TemplateDelete)
+			if err != nil {
+				token.RemoveContext(req.Context())
+				return nil, errInternal("Unable to send confirmation mail").WithCause(err)
+			}
+
+			return nil, nil
+		}
+
+		token, err := verifyToken(req, account, reqData.Confirmation, data.TokenTypeDeletion)
+		if err != nil && err != errInvalidToken {
+			return nil, err
+		}
+
+		if err != nil || time.Since(token.Created) > deleteTokenLifetime {
+			return nil, authFailed(errInvalidConfirmation)
+		}
+
+		// Organizations must not be left without owner
+		if _, err = account.GetSoleOrganizationsContext(req.Context()); err == data.ErrOrganizationOwner {
+			return nil, errConflict("organization_owner", "Transfer ownership of your organizations first")
+		}
+
+		if err != nil {
+			return nil, errInternal("Unable to delete account").WithCause(err)
+		}
+
+		// Cancel paid subscription before removing any data
+		if sub := account.GetSubscriptionContext(req.Context()); sub != nil && sub.StripeID != "" {
+			stripe.Key = conf.StripeKey
+
+			if _, err = stripeSub.Cancel(sub.StripeID, nil); err != nil {
+				return nil, errInternal("Unable to cancel subscription").WithCause(err)
+			}
+		}
+
+		// Organizations only used by the account are removed with it,
+		// personal notes, tokens and subscriptions by the database and notes
+		// of organizations stay with their remaining members
+		if err = account.DeleteContext(req.Context()); err != nil {
+			return nil, errInternal("Unable to delete account").WithCause(err)
+		}
+
+		// Account is gone, a failed goodbye mail does not matter anymore
+		if conf.TemplateGoodbye != 0 {
+			mail.Queue(account.Address, conf.TemplateGoodbye, map[string]interface{}{})
+		}
+
+		return nil, nil
+	},
+	nil,
+}
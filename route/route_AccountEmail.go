@@ -0,0 +1,132 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/clinotes/server/data"
+	"github.com/clinotes/server/mail"
+)
+
+// addressChangeLifetime limits how long a mailed address change token can be used
+const addressChangeLifetime = 24 * time.Hour
+
+// APIRequestStructAccountEmail is
+type APIRequestStructAccountEmail struct {
+	Address      string `json:"address"`
+	Token        string `json:"token"`
+	New          string `json:"new"`
+	Confirmation string `json:"confirmation"`
+}
+
+// APIRouteAccountEmail is
+var APIRouteAccountEmail = Route{
+	"POST",
+	"/account/email",
+	APIRequestStructAccountEmail{},
+	nil,
+	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
+		// Changes are confirmed by mail and disabled without its template
+		if conf.TemplateEmail == 0 {
+			return nil, errNotEnabled
+		}
+
+		// Parse JSON request
+		var reqData APIRequestStructAccountEmail
+		if err := checkJSONBody(req, res, &reqData); err != nil {
+			return nil, err
+		}
+
+		account, err := checkAccount(req, reqData.Address, reqData.Token)
+		if err != nil {
+			return nil, err
+		}
+
+		// Confirmation token from the new address switches the address
+		if reqData.Confirmation != "" {
+			since := time.Now().Add(-addressChangeLifetime)
+
+			for _, change := range data.AddressChangeListByAccountContext(req.Context(), account.ID, since) {
+				if !change.Matches(reqData.Confirmation) {
+					continue
+				}
+
+				_, err = change.ConfirmContext(req.Context())
+				if data.IsDuplicate(err) {
+					return nil, errAddressInUse
+				}
+
+				if err != nil {
+					return nil, errInternal("Unable to change address").WithCause(err)
+				}
+
+				return nil, nil
+			}
+
+			return nil, authFailed(errInvalidConfirmation)
+		}
+
+		address, err := data.ParseAddress(reqData.New)
+		if err != nil {
+			return nil, errAddress("new", err)
+		}
+
+		if address == account.Address {
+			return nil, errInvalid("invalid_address", "Invalid new address")
+		}
+
+		_, err = accountByAddress(req, address)
+		if err == nil {
+			return nil, errAddressInUse
+		}
+
+		if err != errUnknownAccount {
+			return nil, err
+		}
+
+		// Notify the current address before anything changes
+		if conf.TemplateNotice != 0 {
+			err = mail.Queue(account.Address, conf.TemplateNotice, map[string]interface{}{
+				"address": address,
+			})
+
+			if err != nil {
+				return nil, errInternal("Unable to send notice mail").WithCause(err)
+			}
+		}
+
+		change := data.AddressChangeNew(account.ID, address)
+		changeRaw := change.Raw()
+		change, err = change.StoreContext(req.Context())
+
+		if err != nil {
+			return nil, errInternal("Unable to change address").WithCause(err)
+		}
+
+		err = queueTokenWithTemplate(address, changeRaw, conf.TemplateEmail)
+		if err != nil {
+			return nil, errInternal("Unable to send confirmation mail").WithCause(err)
+		}
+
+		return nil, nil
+	},
+	nil,
+}
@@ -0,0 +1,281 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/clinotes/server/data"
+)
+
+// APIRequestStructExportAccount is
+type APIRequestStructExportAccount struct {
+	Address string `json:"address"`
+	Token   string `json:"token"`
+}
+
+// APIResponseStructPersonalData is
+type APIResponseStructPersonalData struct {
+	Account        *data.Account
+	Subscriptions  []*data.Subscription
+	Tokens         []APIResponseStructPersonalToken
+	Memberships    []APIResponseStructPersonalMember
+	Invitations    []APIResponseStructPersonalInvitation
+	Shares         []APIResponseStructPersonalShare
+	AddressChanges []APIResponseStructPersonalAddressChange
+	Messages       []APIResponseStructPersonalMessage
+}
+
+// APIResponseStructPersonalToken is
+type APIResponseStructPersonalToken struct {
+	ID      int
+	Type    int
+	Active  bool
+	Created time.Time
+}
+
+// APIResponseStructPersonalMember is
+type APIResponseStructPersonalMember struct {
+	Organization int
+	Name         string
+	Role         string
+	Created      time.Time
+}
+
+// APIResponseStructPersonalInvitation is
+type APIResponseStructPersonalInvitation struct {
+	Organization int
+	Role         string
+	Created      time.Time
+}
+
+// APIResponseStructPersonalShare is
+type APIResponseStructPersonalShare struct {
+	ID      int
+	Note    int
+	Created time.Time
+	Expires *time.Time
+	Views   int
+	Limit   int
+}
+
+// APIResponseStructPersonalAddressChange is
+type APIResponseStructPersonalAddressChange struct {
+	ID      int
+	Address string
+	Created time.Time
+}
+
+// APIResponseStructPersonalMessage is
+type APIResponseStructPersonalMessage struct {
+	ID       int
+	Address  string
+	Template int64
+	Created  time.Time
+	Sent     *time.Time
+	Failed   *time.Time
+}
+
+// APIResponseStructPersonalNote is
+type APIResponseStructPersonalNote struct {
+	ID           int
+	Text         string
+	Created      time.Time
+	Organization int
+	Revisions    []*data.Revision
+}
+
+// APIRouteAccountExport is
+var APIRouteAccountExport = Route{
+	"POST",
+	"/account/export",
+	APIRequestStructExportAccount{},
+	Stream(nil),
+	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
+		// Parse JSON request
+		var reqData APIRequestStructExportAccount
+		if err := checkJSONBody(req, res, &reqData); err != nil {
+			return nil, err
+		}
+
+		account, err := checkAccount(req, reqData.Address, reqData.Token)
+		if err != nil {
+			return nil, err
+		}
+
+		personal, err := personalData(req.Context(), account)
+		if err != nil {
+			return nil, err
+		}
+
+		// Package personal data and all notes written by the account
+		return Stream(func(w http.ResponseWriter) error {
+			w.Header().Set("Content-Type", "application/zip")
+			w.Header().Set("Content-Disposition", `attachment; filename="clinotes-account.zip"`)
+
+			archive := zip.NewWriter(w)
+
+			file, err := archive.Create("account.json")
+			if err != nil {
+				return err
+			}
+
+			if err = json.NewEncoder(file).Encode(personal); err != nil {
+				return err
+			}
+
+			file, err = archive.Create("notes.jsonl")
+			if err != nil {
+				return err
+			}
+
+			encoder := json.NewEncoder(file)
+			err = data.NoteEachByAuthorContext(req.Context(), account.ID, func(note *data.Note, revisions []*data.Revision) error {
+				return encoder.Encode(APIResponseStructPersonalNote{
+					note.ID,
+					note.Text,
+					note.Created,
+					note.Organization,
+					revisions,
+				})
+			})
+
+			if err != nil {
+				return err
+			}
+
+			return archive.Close()
+		}), nil
+	},
+	nil,
+}
+
+// personalData collects everything stored about account except notes
+func personalData(ctx context.Context, account *data.Account) (*APIResponseStructPersonalData, error) {
+	personal := &APIResponseStructPersonalData{Account: account}
+
+	subscriptions, err := data.SubscriptionListByAccountContext(ctx, account.ID)
+	if err != nil {
+		return nil, errInternal("Unable to export account").WithCause(err)
+	}
+
+	personal.Subscriptions = subscriptions
+
+	for _, tokenType := range []int{data.TokenTypeMaintenace, data.TokenTypeAccess, data.TokenTypeDeletion} {
+		tokens, err := account.GetTokenListContext(ctx, tokenType)
+		if err != nil {
+			return nil, errInternal("Unable to export account").WithCause(err)
+		}
+
+		for _, token := range tokens {
+			personal.Tokens = append(personal.Tokens, APIResponseStructPersonalToken{
+				token.ID,
+				token.Type,
+				token.Active,
+				token.Created,
+			})
+		}
+	}
+
+	memberships, err := data.MemberListByAccountContext(ctx, account.ID)
+	if err != nil {
+		return nil, errInternal("Unable to export account").WithCause(err)
+	}
+
+	for _, member := range memberships {
+		org, err := data.OrganizationByIDContext(ctx, member.Organization)
+		if err != nil {
+			return nil, errInternal("Unable to export account").WithCause(err)
+		}
+
+		personal.Memberships = append(personal.Memberships, APIResponseStructPersonalMember{
+			org.ID,
+			org.Name,
+			roleName(member.Role),
+			member.Created,
+		})
+	}
+
+	for _, invitation := range data.InvitationListByAddressContext(ctx, account.Address) {
+		personal.Invitations = append(personal.Invitations, APIResponseStructPersonalInvitation{
+			invitation.Organization,
+			roleName(invitation.Role),
+			invitation.Created,
+		})
+	}
+
+	shares, err := data.ShareListAllByAccountContext(ctx, account.ID)
+	if err != nil {
+		return nil, errInternal("Unable to export account").WithCause(err)
+	}
+
+	for _, share := range shares {
+		personal.Shares = append(personal.Shares, APIResponseStructPersonalShare{
+			share.ID,
+			share.Note,
+			share.Created,
+			share.Expires,
+			share.Views,
+			share.MaxViews,
+		})
+	}
+
+	// Mails to pending addresses belong to the account as well
+	addresses := []string{account.Address}
+	for _, change := range data.AddressChangeListByAccountContext(ctx, account.ID, time.Time{}) {
+		personal.AddressChanges = append(personal.AddressChanges, APIResponseStructPersonalAddressChange{
+			change.ID,
+			change.Address,
+			change.Created,
+		})
+
+		addresses = append(addresses, change.Address)
+	}
+
+	// Models carry tokens and are left out
+	seen := map[int]bool{}
+	for _, address := range addresses {
+		messages, err := data.MessageListByAddressContext(ctx, address)
+		if err != nil {
+			return nil, errInternal("Unable to export account").WithCause(err)
+		}
+
+		for _, message := range messages {
+			if seen[message.ID] {
+				continue
+			}
+
+			seen[message.ID] = true
+			personal.Messages = append(personal.Messages, APIResponseStructPersonalMessage{
+				message.ID,
+				message.Address,
+				message.Template,
+				message.Created,
+				message.Sent,
+				message.Failed,
+			})
+		}
+	}
+
+	return personal, nil
+}
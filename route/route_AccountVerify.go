@@ -19,7 +19,6 @@
 package route
 
 import (
-	"errors"
 	"net/http"
 
 	"github.com/clinotes/server/data"
@@ -33,7 +32,10 @@ type APIRequestStructVerifyUser struct {
 
 // APIRouteAccountVerify is
 var APIRouteAccountVerify = Route{
+	"POST",
 	"/account/verify",
+	APIRequestStructVerifyUser{},
+	nil,
 	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
 		// Parse JSON request
 		var reqData APIRequestStructVerifyUser
@@ -42,28 +44,29 @@ var APIRouteAccountVerify = Route{
 		}
 
 		// Get account
-		account, err := data.AccountByAddress(reqData.Address)
+		account, err := accountByAddress(req, reqData.Address)
 		if err != nil {
-			return nil, errors.New("Unknown account address")
+			return nil, authFailed(err)
 		}
 
 		// Check if account has requested token
-		_, err = account.GetToken(reqData.Token, data.TokenTypeMaintenace)
+		_, err = verifyToken(req, account, reqData.Token, data.TokenTypeMaintenace)
 		if err != nil {
-			return nil, errors.New("Unable to use provided token")
+			return nil, authFailed(err)
 		}
 
 		// Verify account
-		account, err = account.Verify()
+		account, err = account.VerifyContext(req.Context())
 		if err != nil {
-			return nil, errors.New("Unable to use provided token")
+			return nil, errInternal("Unable to verify account").WithCause(err)
 		}
 
-		_, err = sendTokenWithTemplate(account.Address, reqData.Token, conf.TemplateConfirm)
+		err = queueTokenWithTemplate(account.Address, reqData.Token, conf.TemplateConfirm)
 		if err != nil {
-			return nil, errors.New("Unable to send verification mail")
+			return nil, errInternal("Unable to send verification mail").WithCause(err)
 		}
 
 		return nil, nil
 	},
+	nil,
 }
@@ -0,0 +1,88 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/clinotes/server/data"
+)
+
+// resendInterval is the time to wait before another welcome mail is sent
+const resendInterval = time.Minute
+
+// APIRequestStructVerifyResend is
+type APIRequestStructVerifyResend struct {
+	Address string `json:"address"`
+}
+
+// APIRouteAccountVerifyResend sends a new welcome mail to an unverified
+// account
+var APIRouteAccountVerifyResend = Route{
+	"POST",
+	"/account/verify/resend",
+	APIRequestStructVerifyResend{},
+	nil,
+	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
+		// Parse JSON request
+		var reqData APIRequestStructVerifyResend
+		if err := checkJSONBody(req, res, &reqData); err != nil {
+			return nil, err
+		}
+
+		// Get account
+		account, err := accountByAddress(req, reqData.Address)
+		if err != nil {
+			return nil, err
+		}
+
+		if account.Verified {
+			return nil, errConflict("account_verified", "Account already verified")
+		}
+
+		tokens, err := account.GetTokenListContext(req.Context(), data.TokenTypeMaintenace)
+		if err != nil {
+			return nil, errInternal("Unable to get tokens").WithCause(err)
+		}
+
+		for _, token := range tokens {
+			if time.Since(token.Created) < resendInterval {
+				return nil, errTooManyRequests
+			}
+		}
+
+		token := data.TokenNew(account.ID, data.TokenTypeMaintenace)
+		tokenRaw := token.Raw()
+		token, err = token.StoreContext(req.Context())
+
+		if err != nil {
+			return nil, errInternal("Unable to create token for account").WithCause(err)
+		}
+
+		err = queueTokenWithTemplate(account.Address, tokenRaw, conf.TemplateWelcome)
+		if err != nil {
+			token.RemoveContext(req.Context())
+			return nil, errInternal("Unable to send welcome mail").WithCause(err)
+		}
+
+		return nil, nil
+	},
+	nil,
+}
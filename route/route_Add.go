@@ -19,7 +19,6 @@
 package route
 
 import (
-	"errors"
 	"net/http"
 
 	"github.com/clinotes/server/data"
@@ -30,11 +29,16 @@ type APIRequestStructAdd struct {
 	Address string `json:"address"`
 	Token   string `json:"token"`
 	Note    string `json:"note"`
+
+	Organization int `json:"organization"`
 }
 
 // APIRouteAdd is
 var APIRouteAdd = Route{
+	"POST",
 	"/add",
+	APIRequestStructAdd{},
+	nil,
 	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
 		// Parse JSON request
 		var reqData APIRequestStructAdd
@@ -43,28 +47,43 @@ var APIRouteAdd = Route{
 		}
 
 		// Get account
-		account, err := data.AccountByAddress(reqData.Address)
+		account, err := accountByAddress(req, reqData.Address)
 		if err != nil {
-			return nil, errors.New("Unknown account address")
+			return nil, authFailed(err)
 		}
 
 		if !account.Verified {
-			return nil, errors.New("Account not verified")
+			return nil, authFailed(errNotVerified)
 		}
 
 		// Check if account has requested token
-		_, err = account.GetToken(reqData.Token, data.TokenTypeAccess)
+		accessToken, err := verifyToken(req, account, reqData.Token, data.TokenTypeAccess)
 		if err != nil {
-			return nil, errors.New("Unable to use provided token")
+			return nil, authFailed(err)
 		}
 
 		note := data.NoteNew(account.ID, reqData.Note)
-		note, err = note.Store()
+
+		// Notes of an organization can be added by editors
+		if reqData.Organization != 0 {
+			if _, err = checkMember(req, account, reqData.Organization, data.RoleEditor); err != nil {
+				return nil, err
+			}
+
+			note = data.NoteOrganizationNew(reqData.Organization, account.ID, reqData.Note)
+		}
+
+		if err = note.Validate(); err != nil {
+			return nil, errInvalid("invalid_note", "Invalid note").WithDetail("note", err.Error())
+		}
+
+		note, err = note.StoreWithTokenContext(req.Context(), accessToken.ID)
 
 		if err != nil {
-			return nil, errors.New("Unable to store note")
+			return nil, errInternal("Unable to store note").WithCause(err)
 		}
 
 		return nil, nil
 	},
+	nil,
 }
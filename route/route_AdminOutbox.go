@@ -0,0 +1,86 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/clinotes/server/data"
+)
+
+// adminOutboxLimit is the number of failed messages listed
+const adminOutboxLimit = 100
+
+// APIRequestStructAdminOutbox is
+type APIRequestStructAdminOutbox struct {
+	Token string `json:"token"`
+}
+
+// APIResponseStructMessage is
+type APIResponseStructMessage struct {
+	ID       int        `json:"id"`
+	Address  string     `json:"address"`
+	Template int64      `json:"template"`
+	Created  time.Time  `json:"created"`
+	Attempts int        `json:"attempts"`
+	Failed   *time.Time `json:"failed"`
+	Error    string     `json:"error"`
+}
+
+// APIRouteAdminOutbox lists messages which failed to be delivered
+var APIRouteAdminOutbox = Route{
+	"POST",
+	"/admin/outbox",
+	APIRequestStructAdminOutbox{},
+	[]APIResponseStructMessage{},
+	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
+		// Parse JSON request
+		var reqData APIRequestStructAdminOutbox
+		if err := checkJSONBody(req, res, &reqData); err != nil {
+			return nil, err
+		}
+
+		if err := checkAdmin(reqData.Token); err != nil {
+			return nil, err
+		}
+
+		list, err := data.MessageListFailedContext(req.Context(), adminOutboxLimit)
+		if err != nil {
+			return nil, errInternal("Failed to get messages").WithCause(err)
+		}
+
+		// Models are not listed, they may contain tokens
+		var messageList []APIResponseStructMessage
+		for _, message := range list {
+			messageList = append(messageList, APIResponseStructMessage{
+				message.ID,
+				message.Address,
+				message.Template,
+				message.Created,
+				message.Attempts,
+				message.Failed,
+				message.Error,
+			})
+		}
+
+		return messageList, nil
+	},
+	nil,
+}
@@ -0,0 +1,65 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"net/http"
+
+	"github.com/clinotes/server/data"
+	"github.com/clinotes/server/mail"
+)
+
+// APIRequestStructAdminOutboxRetry is
+type APIRequestStructAdminOutboxRetry struct {
+	Token string `json:"token"`
+	ID    int    `json:"id"`
+}
+
+// APIRouteAdminOutboxRetry requeues a message which failed to be delivered
+var APIRouteAdminOutboxRetry = Route{
+	"POST",
+	"/admin/outbox/retry",
+	APIRequestStructAdminOutboxRetry{},
+	nil,
+	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
+		// Parse JSON request
+		var reqData APIRequestStructAdminOutboxRetry
+		if err := checkJSONBody(req, res, &reqData); err != nil {
+			return nil, err
+		}
+
+		if err := checkAdmin(reqData.Token); err != nil {
+			return nil, err
+		}
+
+		message, err := data.MessageByIDContext(req.Context(), reqData.ID)
+		if err != nil || !message.IsFailed() {
+			return nil, errNotFound("unknown_message", "Unknown failed message")
+		}
+
+		if _, err = message.RequeueContext(req.Context()); err != nil {
+			return nil, errInternal("Unable to requeue message").WithCause(err)
+		}
+
+		mail.Wake()
+
+		return nil, nil
+	},
+	nil,
+}
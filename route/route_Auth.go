@@ -19,7 +19,6 @@
 package route
 
 import (
-	"errors"
 	"net/http"
 
 	"github.com/clinotes/server/data"
@@ -33,7 +32,10 @@ type APIRequestStructAuth struct {
 
 // APIRouteAuth is
 var APIRouteAuth = Route{
+	"POST",
 	"/auth",
+	APIRequestStructAuth{},
+	nil,
 	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
 		// Parse JSON request
 		var reqData APIRequestStructAuth
@@ -42,21 +44,22 @@ var APIRouteAuth = Route{
 		}
 
 		// Get account
-		account, err := data.AccountByAddress(reqData.Address)
+		account, err := accountByAddress(req, reqData.Address)
 		if err != nil {
-			return nil, errors.New("Unknown account address")
+			return nil, authFailed(err)
 		}
 
 		if !account.Verified {
-			return nil, errors.New("Account not verified")
+			return nil, authFailed(errNotVerified)
 		}
 
 		// Check if account has requested token
-		_, err = account.GetToken(reqData.Token, data.TokenTypeAccess)
+		_, err = verifyToken(req, account, reqData.Token, data.TokenTypeAccess)
 		if err != nil {
-			return nil, errors.New("Unable to use provided token")
+			return nil, authFailed(err)
 		}
 
 		return nil, nil
 	},
+	nil,
 }
@@ -0,0 +1,179 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/clinotes/server/data"
+	stripe "github.com/stripe/stripe-go"
+	stripeSub "github.com/stripe/stripe-go/sub"
+)
+
+// APIRequestStructInvoices is
+type APIRequestStructInvoices struct {
+	Address string `json:"address"`
+	Token   string `json:"token"`
+}
+
+// APIResponseStructInvoice is
+type APIResponseStructInvoice struct {
+	Number   string
+	Amount   int64
+	Currency string
+	Status   string
+	Created  time.Time
+	PDF      string
+}
+
+// stripeInvoice holds the invoice fields not available in stripe.Invoice
+type stripeInvoice struct {
+	Number   string `json:"number"`
+	Receipt  string `json:"receipt_number"`
+	Amount   int64  `json:"amount_due"`
+	Currency string `json:"currency"`
+	Status   string `json:"status"`
+	Paid     bool   `json:"paid"`
+	Date     int64  `json:"date"`
+	PDF      string `json:"invoice_pdf"`
+}
+
+type stripeInvoiceList struct {
+	stripe.ListMeta
+	Values []stripeInvoice `json:"data"`
+}
+
+// APIRouteBillingInvoices is
+var APIRouteBillingInvoices = Route{
+	"POST",
+	"/billing/invoices",
+	APIRequestStructInvoices{},
+	[]APIResponseStructInvoice{},
+	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
+		// Parse JSON request
+		var reqData APIRequestStructInvoices
+		if err := checkJSONBody(req, res, &reqData); err != nil {
+			return nil, err
+		}
+
+		// Get account
+		account, err := accountByAddress(req, reqData.Address)
+		if err != nil {
+			return nil, authFailed(err)
+		}
+
+		if !account.Verified {
+			return nil, authFailed(errNotVerified)
+		}
+
+		// Check if account has requested token
+		_, err = verifyToken(req, account, reqData.Token, data.TokenTypeAccess)
+		if err != nil {
+			return nil, authFailed(err)
+		}
+
+		account, err = stripeCustomerOf(req, account)
+		if err != nil {
+			return nil, err
+		}
+
+		if account.Customer == "" {
+			return nil, errNotFound("no_billing", "No billing information for account")
+		}
+
+		stripe.Key = conf.StripeKey
+		params := &stripe.RequestValues{}
+		params.Add("customer", account.Customer)
+		params.Add("limit", "100")
+
+		var list stripeInvoiceList
+		err = stripe.GetBackend(stripe.APIBackend).Call("GET", "/invoices", stripe.Key, params, nil, &list)
+		if err != nil {
+			return nil, errInternal("Failed to get invoices").WithCause(err)
+		}
+
+		var invoiceList []APIResponseStructInvoice
+		for _, item := range list.Values {
+			number := item.Number
+			if number == "" {
+				number = item.Receipt
+			}
+
+			status := item.Status
+			if status == "" && item.Paid {
+				status = "paid"
+			} else if status == "" {
+				status = "open"
+			}
+
+			invoiceList = append(invoiceList, APIResponseStructInvoice{
+				number,
+				item.Amount,
+				item.Currency,
+				status,
+				time.Unix(item.Date, 0),
+				item.PDF,
+			})
+		}
+
+		return invoiceList, nil
+	},
+	nil,
+}
+
+// stripeCustomerOf returns account with its Stripe customer, accounts which
+// subscribed before customers were stored get it from their latest Stripe
+// subscription
+func stripeCustomerOf(req *http.Request, account *data.Account) (*data.Account, error) {
+	if account.Customer != "" || conf.StripeKey == "" {
+		return account, nil
+	}
+
+	list, err := data.SubscriptionListByAccountContext(req.Context(), account.ID)
+	if err != nil {
+		return nil, errInternal("Unable to get subscription").WithCause(err)
+	}
+
+	stripe.Key = conf.StripeKey
+	for i := len(list) - 1; i >= 0; i-- {
+		if list[i].StripeID == "" {
+			continue
+		}
+
+		s, err := stripeSub.Get(list[i].StripeID, nil)
+		if err != nil {
+			return nil, errInternal("Unable to get subscription").WithCause(err)
+		}
+
+		if s.Customer == nil || s.Customer.ID == "" {
+			continue
+		}
+
+		account.Customer = s.Customer.ID
+		account, err = account.StoreContext(req.Context())
+		if err != nil {
+			return nil, errInternal("Invalid account information").WithCause(err)
+		}
+
+		return account, nil
+	}
+
+	return account, nil
+}
@@ -0,0 +1,228 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/clinotes/server/data"
+)
+
+// APIRequestStructExport is
+type APIRequestStructExport struct {
+	Address      string `json:"address"`
+	Token        string `json:"token"`
+	Format       string `json:"format"`
+	Organization int    `json:"organization"`
+}
+
+// APIResponseStructExportNote is
+type APIResponseStructExportNote struct {
+	ID           int       `json:"id"`
+	Text         string    `json:"text"`
+	Created      time.Time `json:"created"`
+	Organization int       `json:"organization,omitempty"`
+}
+
+// exporter writes notes in an export format
+type exporter interface {
+	Write(note *data.Note) error
+	Close() error
+}
+
+// exportFormats maps format names to content type, file extension and
+// exporter
+var exportFormats = map[string]struct {
+	ContentType string
+	Extension   string
+	New         func(io.Writer) exporter
+}{
+	"json":     {"application/x-ndjson", "jsonl", newExporterJSON},
+	"csv":      {"text/csv; charset=utf-8", "csv", newExporterCSV},
+	"markdown": {"application/zip", "zip", newExporterMarkdown},
+}
+
+// APIRouteExport is
+var APIRouteExport = Route{
+	"POST",
+	"/export",
+	APIRequestStructExport{},
+	Stream(nil),
+	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
+		// Parse JSON request
+		var reqData APIRequestStructExport
+		if err := checkJSONBody(req, res, &reqData); err != nil {
+			return nil, err
+		}
+
+		account, err := checkAccount(req, reqData.Address, reqData.Token)
+		if err != nil {
+			return nil, err
+		}
+
+		if reqData.Format == "" {
+			reqData.Format = "json"
+		}
+
+		format, ok := exportFormats[reqData.Format]
+		if !ok {
+			return nil, errInvalid("unknown_format", "Unknown export format")
+		}
+
+		// Notes of an organization can be exported by all members
+		each := func(fn func(*data.Note) error) error {
+			return data.NoteEachByAccountContext(req.Context(), account.ID, fn)
+		}
+
+		if reqData.Organization != 0 {
+			if _, err = checkMember(req, account, reqData.Organization, data.RoleViewer); err != nil {
+				return nil, err
+			}
+
+			each = func(fn func(*data.Note) error) error {
+				return data.NoteEachByOrganizationContext(req.Context(), reqData.Organization, fn)
+			}
+		}
+
+		return Stream(func(w http.ResponseWriter) error {
+			w.Header().Set("Content-Type", format.ContentType)
+			w.Header().Set("Content-Disposition", `attachment; filename="clinotes.`+format.Extension+`"`)
+
+			out := format.New(w)
+			if err := each(out.Write); err != nil {
+				return err
+			}
+
+			return out.Close()
+		}), nil
+	},
+	nil,
+}
+
+// ExportNotes writes all personal notes of account to w in format
+func ExportNotes(ctx context.Context, w io.Writer, format string, account int) error {
+	f, ok := exportFormats[format]
+	if !ok {
+		return errInvalid("unknown_format", "Unknown export format")
+	}
+
+	out := f.New(w)
+	if err := data.NoteEachByAccountContext(ctx, account, out.Write); err != nil {
+		return err
+	}
+
+	return out.Close()
+}
+
+func exportNote(note *data.Note) APIResponseStructExportNote {
+	return APIResponseStructExportNote{note.ID, note.Text, note.Created, note.Organization}
+}
+
+type exporterJSON struct {
+	encoder *json.Encoder
+}
+
+func newExporterJSON(w io.Writer) exporter {
+	return &exporterJSON{json.NewEncoder(w)}
+}
+
+func (e *exporterJSON) Write(note *data.Note) error {
+	return e.encoder.Encode(exportNote(note))
+}
+
+func (e *exporterJSON) Close() error {
+	return nil
+}
+
+type exporterCSV struct {
+	writer *csv.Writer
+	header bool
+}
+
+func newExporterCSV(w io.Writer) exporter {
+	return &exporterCSV{csv.NewWriter(w), false}
+}
+
+func (e *exporterCSV) Write(note *data.Note) error {
+	if !e.header {
+		e.header = true
+
+		if err := e.writer.Write([]string{"id", "created", "organization", "text"}); err != nil {
+			return err
+		}
+	}
+
+	return e.writer.Write([]string{
+		strconv.Itoa(note.ID),
+		note.Created.Format(time.RFC3339),
+		strconv.Itoa(note.Organization),
+		note.Text,
+	})
+}
+
+func (e *exporterCSV) Close() error {
+	e.writer.Flush()
+
+	return e.writer.Error()
+}
+
+// exporterMarkdown writes one Markdown file per day into a zip archive;
+// notes arrive ordered by date, so a day is complete once the next starts
+type exporterMarkdown struct {
+	archive *zip.Writer
+	file    io.Writer
+	day     string
+}
+
+func newExporterMarkdown(w io.Writer) exporter {
+	return &exporterMarkdown{zip.NewWriter(w), nil, ""}
+}
+
+func (e *exporterMarkdown) Write(note *data.Note) error {
+	day := note.Created.Format("2006-01-02")
+
+	if day != e.day {
+		file, err := e.archive.Create(day + ".md")
+		if err != nil {
+			return err
+		}
+
+		if _, err = io.WriteString(file, "# "+day+"\n"); err != nil {
+			return err
+		}
+
+		e.file = file
+		e.day = day
+	}
+
+	_, err := io.WriteString(e.file, "\n## "+note.Created.Format("15:04:05")+"\n\n"+note.Text+"\n")
+
+	return err
+}
+
+func (e *exporterMarkdown) Close() error {
+	return e.archive.Close()
+}
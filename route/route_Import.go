@@ -0,0 +1,361 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clinotes/server/data"
+)
+
+// importLimit is the maximum size of uploaded files and of every file in an
+// uploaded archive, importArchiveLimit of all files in it once decompressed
+const (
+	importLimit        = 10 << 20
+	importArchiveLimit = 5 * importLimit
+)
+
+// APIRequestStructImport is sent as multipart form
+type APIRequestStructImport struct {
+	Address      string `json:"address"`
+	Token        string `json:"token"`
+	Organization int    `json:"organization"`
+	File         []byte `json:"file"`
+}
+
+func (APIRequestStructImport) form() {}
+
+// APIResponseStructImport is
+type APIResponseStructImport struct {
+	Imported int
+	Skipped  int
+	Failed   int
+	Items    []APIResponseStructImportItem
+}
+
+// APIResponseStructImportItem is
+type APIResponseStructImportItem struct {
+	Source string
+	Status string
+	Text   string
+	Error  string `json:",omitempty"`
+}
+
+// importEntry is a single note read from an uploaded file
+type importEntry struct {
+	Source  string
+	Text    string
+	Created time.Time
+	Err     error
+}
+
+// APIRouteImport is
+var APIRouteImport = Route{
+	"POST",
+	"/import",
+	APIRequestStructImport{},
+	APIResponseStructImport{},
+	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
+		// Parse multipart request with the uploaded file
+		req.Body = http.MaxBytesReader(res, req.Body, importLimit)
+		if err := req.ParseMultipartForm(importLimit); err != nil {
+			return nil, newError(http.StatusBadRequest, "invalid_upload", "Invalid upload")
+		}
+
+		account, accessToken, err := checkToken(req, req.FormValue("address"), req.FormValue("token"))
+		if err != nil {
+			return nil, err
+		}
+
+		// Notes of an organization can be imported by editors
+		organization, _ := strconv.Atoi(req.FormValue("organization"))
+		if organization != 0 {
+			if _, err = checkMember(req, account, organization, data.RoleEditor); err != nil {
+				return nil, err
+			}
+		}
+
+		file, header, err := req.FormFile("file")
+		if err != nil {
+			return nil, errInvalid("missing_file", "Missing import file")
+		}
+
+		defer file.Close()
+
+		var entries []importEntry
+		if strings.ToLower(path.Ext(header.Filename)) == ".zip" {
+			archive, err := zip.NewReader(file, header.Size)
+			if err != nil {
+				return nil, errInvalid("invalid_archive", "Invalid zip archive")
+			}
+
+			if entries, err = parseArchive(archive); err != nil {
+				return nil, err
+			}
+		} else {
+			entries = parseImport(header.Filename, file, time.Now())
+		}
+
+		// Check all entries before writing the valid ones at once
+		report, notes, items := checkImport(entries, organization, account.ID)
+
+		written, err := data.NoteImportContext(req.Context(), notes, accessToken.ID)
+		if err != nil {
+			return nil, errInternal("Unable to import notes").WithCause(err)
+		}
+
+		for i, ok := range written {
+			if ok {
+				report.Items[items[i]].Status = "imported"
+			} else {
+				report.Items[items[i]].Status = "skipped"
+				report.Items[items[i]].Error = "Note already exists"
+			}
+		}
+
+		report.count()
+
+		return report, nil
+	},
+	nil,
+}
+
+// checkImport reports the entries which cannot be imported and returns the
+// Note of all others with the index of their item in the report
+func checkImport(entries []importEntry, organization int, account int) (*APIResponseStructImport, []*data.Note, []int) {
+	report := &APIResponseStructImport{}
+	var notes []*data.Note
+	var items []int
+
+	for _, entry := range entries {
+		item := APIResponseStructImportItem{entry.Source, "failed", entry.Text, ""}
+
+		note := data.NoteOrganizationNew(organization, account, entry.Text)
+		note.Created = entry.Created
+
+		if entry.Err != nil {
+			item.Error = entry.Err.Error()
+		} else if entry.Text == "" {
+			item.Status = "skipped"
+			item.Error = "Empty note"
+		} else if err := note.Validate(); err != nil {
+			item.Error = err.Error()
+		} else {
+			notes = append(notes, note)
+			items = append(items, len(report.Items))
+		}
+
+		report.Items = append(report.Items, item)
+	}
+
+	return report, notes, items
+}
+
+// count sums up the items of the report by status
+func (r *APIResponseStructImport) count() {
+	for _, item := range r.Items {
+		switch item.Status {
+		case "imported":
+			r.Imported++
+		case "skipped":
+			r.Skipped++
+		default:
+			r.Failed++
+		}
+	}
+}
+
+// parseArchive reads entries from all files of archive
+func parseArchive(archive *zip.Reader) ([]importEntry, error) {
+	var entries []importEntry
+	budget := uint64(importArchiveLimit)
+
+	for _, f := range archive.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		// Reads are limited to the declared size, so rejecting large
+		// declarations keeps archives from expanding without bound
+		if f.UncompressedSize64 > importLimit || f.UncompressedSize64 > budget {
+			return nil, errInvalid("archive_too_large", "Archive expands beyond the import limit").WithDetail("file", f.Name)
+		}
+
+		budget -= f.UncompressedSize64
+
+		r, err := f.Open()
+		if err != nil {
+			entries = append(entries, importEntry{Source: f.Name, Err: err})
+			continue
+		}
+
+		entries = append(entries, parseImport(f.Name, io.LimitReader(r, int64(f.UncompressedSize64)), f.ModTime())...)
+		r.Close()
+	}
+
+	return entries, nil
+}
+
+// parseImport reads entries from a file depending on its extension
+func parseImport(name string, r io.Reader, modified time.Time) []importEntry {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".json", ".jsonl":
+		return parseImportJSON(name, r, modified)
+	case ".md", ".markdown":
+		return parseImportMarkdown(name, r, modified)
+	case ".txt", ".text":
+		return parseImportText(name, r, modified)
+	}
+
+	return []importEntry{{Source: name, Err: errors.New("Unsupported file type")}}
+}
+
+// parseImportJSON reads one note per line as written by the JSON export
+func parseImportJSON(name string, r io.Reader, modified time.Time) []importEntry {
+	var list []importEntry
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		var item APIResponseStructExportNote
+		entry := importEntry{Source: name + ":" + strconv.Itoa(line), Created: modified}
+
+		if err := json.Unmarshal(scanner.Bytes(), &item); err != nil {
+			entry.Err = errInvalidJSON
+		} else {
+			entry.Text = strings.TrimSpace(item.Text)
+
+			if !item.Created.IsZero() {
+				entry.Created = item.Created
+			}
+		}
+
+		list = append(list, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		list = append(list, importEntry{Source: name, Err: err})
+	}
+
+	return list
+}
+
+// parseImportMarkdown reads notes from Markdown files as written by the
+// Markdown export, one file per day with a heading per note; files without
+// note headings are read by paragraph
+func parseImportMarkdown(name string, r io.Reader, modified time.Time) []importEntry {
+	var list []importEntry
+	var entry *importEntry
+	var text []string
+
+	day := modified
+	if date, err := time.Parse("2006-01-02", strings.TrimSuffix(path.Base(name), path.Ext(name))); err == nil {
+		day = date
+	}
+
+	flush := func() {
+		if entry != nil {
+			entry.Text = strings.TrimSpace(strings.Join(text, "\n"))
+			list = append(list, *entry)
+		}
+
+		entry = nil
+		text = nil
+	}
+
+	headings := false
+	scanner := bufio.NewScanner(r)
+
+	for line := 1; scanner.Scan(); line++ {
+		current := scanner.Text()
+		source := name + ":" + strconv.Itoa(line)
+
+		switch {
+		case strings.HasPrefix(current, "## "):
+			flush()
+			headings = true
+			entry = &importEntry{Source: source, Created: day}
+
+			clock := strings.TrimSpace(current[3:])
+			for _, layout := range []string{"15:04:05", "15:04"} {
+				if t, err := time.Parse(layout, clock); err == nil {
+					entry.Created = time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), t.Second(), 0, day.Location())
+					break
+				}
+			}
+		case strings.HasPrefix(current, "# "):
+			flush()
+
+			if date, err := time.Parse("2006-01-02", strings.TrimSpace(current[2:])); err == nil {
+				day = date
+			}
+		case headings:
+			text = append(text, current)
+		case strings.TrimSpace(current) == "":
+			flush()
+		default:
+			if entry == nil {
+				entry = &importEntry{Source: source, Created: day}
+			}
+
+			text = append(text, current)
+		}
+	}
+
+	flush()
+
+	if err := scanner.Err(); err != nil {
+		list = append(list, importEntry{Source: name, Err: err})
+	}
+
+	return list
+}
+
+// parseImportText reads one note per line of plain text
+func parseImportText(name string, r io.Reader, modified time.Time) []importEntry {
+	var list []importEntry
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		list = append(list, importEntry{Source: name + ":" + strconv.Itoa(line), Text: text, Created: modified})
+	}
+
+	if err := scanner.Err(); err != nil {
+		list = append(list, importEntry{Source: name, Err: err})
+	}
+
+	return list
+}
@@ -0,0 +1,137 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseArchive(t *testing.T) {
+	open := func(files map[string][]byte) *zip.Reader {
+		var buf bytes.Buffer
+		w := zip.NewWriter(&buf)
+
+		for name, content := range files {
+			f, _ := w.Create(name)
+			f.Write(content)
+		}
+
+		w.Close()
+
+		archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		assert.Nil(t, err)
+
+		return archive
+	}
+
+	entries, err := parseArchive(open(map[string][]byte{"notes.txt": []byte("first\nsecond\n")}))
+	if assert.Nil(t, err) {
+		assert.Len(t, entries, 2)
+		assert.Equal(t, "second", entries[1].Text)
+	}
+
+	_, err = parseArchive(open(map[string][]byte{"bomb.txt": make([]byte, importLimit+1)}))
+	assert.Equal(t, "archive_too_large", toError(err).Code)
+
+	files := map[string][]byte{}
+	for _, name := range []string{"a.txt", "b.txt", "c.txt", "d.txt", "e.txt", "f.txt"} {
+		files[name] = make([]byte, importLimit)
+	}
+
+	_, err = parseArchive(open(files))
+	assert.Equal(t, "archive_too_large", toError(err).Code)
+}
+
+func TestParseImportJSON(t *testing.T) {
+	modified := time.Date(2021, 6, 7, 8, 9, 10, 0, time.UTC)
+	input := strings.Join([]string{
+		`{"id":1,"text":"kept","created":"2020-01-02T03:04:05Z"}`,
+		`not json`,
+		``,
+		`{"id":2,"text":"  "}`,
+		`{"id":3,"text":"` + strings.Repeat("x", 101) + `"}`,
+		`{"id":4,"text":"undated"}`,
+	}, "\n")
+
+	entries := parseImportJSON("notes.jsonl", strings.NewReader(input), modified)
+	if !assert.Len(t, entries, 5) {
+		return
+	}
+
+	assert.Equal(t, "notes.jsonl:1", entries[0].Source)
+	assert.Equal(t, "kept", entries[0].Text)
+	assert.Equal(t, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), entries[0].Created)
+	assert.Equal(t, errInvalidJSON, entries[1].Err)
+	assert.Equal(t, "notes.jsonl:4", entries[2].Source)
+	assert.Equal(t, modified, entries[4].Created)
+
+	report, notes, items := checkImport(entries, 0, 1)
+	if assert.Len(t, notes, 2) {
+		assert.Equal(t, []int{0, 4}, items)
+		assert.Equal(t, entries[0].Created, notes[0].Created)
+	}
+
+	assert.Equal(t, "failed", report.Items[1].Status)
+	assert.Equal(t, errInvalidJSON.Error(), report.Items[1].Error)
+	assert.Equal(t, "skipped", report.Items[2].Status)
+	assert.Equal(t, "Empty note", report.Items[2].Error)
+	assert.Equal(t, "failed", report.Items[3].Status)
+	assert.Equal(t, "Note must not be longer than 100 characters", report.Items[3].Error)
+
+	for _, i := range items {
+		report.Items[i].Status = "imported"
+	}
+
+	report.count()
+	assert.Equal(t, 2, report.Imported)
+	assert.Equal(t, 1, report.Skipped)
+	assert.Equal(t, 2, report.Failed)
+}
+
+func TestParseImportMarkdown(t *testing.T) {
+	modified := time.Date(2021, 6, 7, 8, 9, 10, 0, time.UTC)
+	input := "# 2024-03-05\n\n## 09:15\n\nfirst note\n\n## 10:30:45\n\nsecond\nline\n\n## 11:00\n\n"
+
+	entries := parseImportMarkdown("2024-03-05.md", strings.NewReader(input), modified)
+	if assert.Len(t, entries, 3) {
+		assert.Equal(t, "2024-03-05.md:3", entries[0].Source)
+		assert.Equal(t, "first note", entries[0].Text)
+		assert.Equal(t, time.Date(2024, 3, 5, 9, 15, 0, 0, time.UTC), entries[0].Created)
+		assert.Equal(t, "second\nline", entries[1].Text)
+		assert.Equal(t, time.Date(2024, 3, 5, 10, 30, 45, 0, time.UTC), entries[1].Created)
+		assert.Equal(t, "", entries[2].Text)
+
+		report, notes, _ := checkImport(entries, 0, 1)
+		assert.Len(t, notes, 2)
+		assert.Equal(t, "skipped", report.Items[2].Status)
+	}
+
+	entries = parseImportMarkdown("notes.md", strings.NewReader("one\ntwo\n\nthree\n"), modified)
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "one\ntwo", entries[0].Text)
+		assert.Equal(t, "notes.md:4", entries[1].Source)
+		assert.Equal(t, modified, entries[1].Created)
+	}
+}
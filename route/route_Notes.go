@@ -19,7 +19,6 @@
 package route
 
 import (
-	"errors"
 	"net/http"
 	"time"
 
@@ -30,17 +29,23 @@ import (
 type APIRequestStructNotes struct {
 	Address string `json:"address"`
 	Token   string `json:"token"`
+
+	Organization int `json:"organization"`
 }
 
 // APIResponseStructNote is
 type APIResponseStructNote struct {
+	ID      int
 	Text    string
 	Created time.Time
 }
 
 // APIRouteNotes is
 var APIRouteNotes = Route{
+	"POST",
 	"/notes",
+	APIRequestStructNotes{},
+	[]APIResponseStructNote{},
 	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
 		// Parse JSON request
 		var reqData APIRequestStructNotes
@@ -49,31 +54,44 @@ var APIRouteNotes = Route{
 		}
 
 		// Get account
-		account, err := data.AccountByAddress(reqData.Address)
+		account, err := accountByAddress(req, reqData.Address)
 		if err != nil {
-			return nil, errors.New("Unknown account address")
+			return nil, authFailed(err)
 		}
 
 		if !account.Verified {
-			return nil, errors.New("Account not verified")
+			return nil, authFailed(errNotVerified)
 		}
 
 		// Check if account has requested token
-		_, err = account.GetToken(reqData.Token, data.TokenTypeAccess)
+		_, err = verifyToken(req, account, reqData.Token, data.TokenTypeAccess)
 		if err != nil {
-			return nil, errors.New("Unable to use provided token")
+			return nil, authFailed(err)
+		}
+
+		var list []data.Note
+
+		// Notes of an organization can be read by all members
+		if reqData.Organization != 0 {
+			if _, err = checkMember(req, account, reqData.Organization, data.RoleViewer); err != nil {
+				return nil, err
+			}
+
+			list, err = data.NoteListByOrganizationContext(req.Context(), reqData.Organization)
+		} else {
+			list, err = data.NoteListByAccountContext(req.Context(), account.ID)
 		}
 
-		list, err := data.NoteListByAccount(account.ID)
 		if err != nil {
-			return nil, errors.New("Failed to get notes")
+			return nil, errInternal("Failed to get notes").WithCause(err)
 		}
 
 		var noteList []APIResponseStructNote
 		for i := 0; i < len(list); i++ {
-			noteList = append(noteList, APIResponseStructNote{list[i].Text, list[i].Created})
+			noteList = append(noteList, APIResponseStructNote{list[i].ID, list[i].Text, list[i].Created})
 		}
 
 		return noteList, nil
 	},
+	nil,
 }
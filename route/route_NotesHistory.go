@@ -0,0 +1,90 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/clinotes/server/data"
+)
+
+// APIRequestStructNotesHistory is
+type APIRequestStructNotesHistory struct {
+	Address string `json:"address"`
+	Token   string `json:"token"`
+	Note    int    `json:"note"`
+}
+
+// APIResponseStructRevision is
+type APIResponseStructRevision struct {
+	ID      int
+	Text    string
+	Created time.Time
+	Token   int
+	Diff    []data.Change
+}
+
+// APIRouteNotesHistory is
+var APIRouteNotesHistory = Route{
+	"POST",
+	"/notes/history",
+	APIRequestStructNotesHistory{},
+	[]APIResponseStructRevision{},
+	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
+		// Parse JSON request
+		var reqData APIRequestStructNotesHistory
+		if err := checkJSONBody(req, res, &reqData); err != nil {
+			return nil, err
+		}
+
+		account, err := checkAccount(req, reqData.Address, reqData.Token)
+		if err != nil {
+			return nil, err
+		}
+
+		note, err := checkNote(req, account, reqData.Note, data.RoleViewer)
+		if err != nil {
+			return nil, err
+		}
+
+		list, err := note.GetRevisionListContext(req.Context())
+		if err != nil {
+			return nil, errInternal("Failed to get note history").WithCause(err)
+		}
+
+		// Every revision carries the changes to its predecessor
+		var revisionList []APIResponseStructRevision
+		var previous *data.Revision
+		for _, revision := range list {
+			revisionList = append(revisionList, APIResponseStructRevision{
+				revision.ID,
+				revision.Text,
+				revision.Created,
+				revision.Token,
+				revision.Diff(previous),
+			})
+
+			previous = revision
+		}
+
+		return revisionList, nil
+	},
+	nil,
+}
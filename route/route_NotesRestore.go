@@ -0,0 +1,74 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"net/http"
+
+	"github.com/clinotes/server/data"
+)
+
+// APIRequestStructNotesRestore is
+type APIRequestStructNotesRestore struct {
+	Address  string `json:"address"`
+	Token    string `json:"token"`
+	Note     int    `json:"note"`
+	Revision int    `json:"revision"`
+}
+
+// APIRouteNotesRestore is
+var APIRouteNotesRestore = Route{
+	"POST",
+	"/notes/restore",
+	APIRequestStructNotesRestore{},
+	APIResponseStructNote{},
+	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
+		// Parse JSON request
+		var reqData APIRequestStructNotesRestore
+		if err := checkJSONBody(req, res, &reqData); err != nil {
+			return nil, err
+		}
+
+		account, accessToken, err := checkToken(req, reqData.Address, reqData.Token)
+		if err != nil {
+			return nil, err
+		}
+
+		note, err := checkNote(req, account, reqData.Note, data.RoleEditor)
+		if err != nil {
+			return nil, err
+		}
+
+		revision, err := data.RevisionByIDContext(req.Context(), reqData.Revision)
+		if err != nil || revision.Note != note.ID {
+			return nil, errNotFound("unknown_revision", "Unknown revision")
+		}
+
+		// Restoring is a change as well and creates a new revision
+		note.Text = revision.Text
+		note, err = note.StoreWithTokenContext(req.Context(), accessToken.ID)
+
+		if err != nil {
+			return nil, errInternal("Unable to restore note").WithCause(err)
+		}
+
+		return APIResponseStructNote{note.ID, note.Text, note.Created}, nil
+	},
+	nil,
+}
@@ -0,0 +1,78 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/clinotes/server/data"
+)
+
+// APIRequestStructCreateOrganization is
+type APIRequestStructCreateOrganization struct {
+	Address string `json:"address"`
+	Token   string `json:"token"`
+	Name    string `json:"name"`
+}
+
+// APIRouteOrganizationCreate is
+var APIRouteOrganizationCreate = Route{
+	"POST",
+	"/organization/create",
+	APIRequestStructCreateOrganization{},
+	APIResponseStructOrganization{},
+	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
+		// Parse JSON request
+		var reqData APIRequestStructCreateOrganization
+		if err := checkJSONBody(req, res, &reqData); err != nil {
+			return nil, err
+		}
+
+		account, err := checkAccount(req, reqData.Address, reqData.Token)
+		if err != nil {
+			return nil, err
+		}
+
+		name := strings.TrimSpace(reqData.Name)
+		if name == "" {
+			return nil, errInvalid("invalid_name", "Organization name must not be empty")
+		}
+
+		org := data.OrganizationNew(name)
+		org, err = org.StoreContext(req.Context())
+
+		if err != nil {
+			return nil, errInternal("Unable to create organization").WithCause(err)
+		}
+
+		// Creator of the organization becomes its owner
+		member := data.MemberNew(org.ID, account.ID, data.RoleOwner)
+		member, err = member.StoreContext(req.Context())
+
+		// If owner cannot be added, fail and remove organization
+		if err != nil {
+			org.RemoveContext(req.Context())
+			return nil, errInternal("Unable to create organization").WithCause(err)
+		}
+
+		return APIResponseStructOrganization{org.ID, org.Name, roleName(member.Role), org.Created}, nil
+	},
+	nil,
+}
@@ -0,0 +1,106 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"net/http"
+
+	"github.com/clinotes/server/data"
+	"github.com/clinotes/server/mail"
+)
+
+// APIRequestStructInviteOrganization is
+type APIRequestStructInviteOrganization struct {
+	Address      string `json:"address"`
+	Token        string `json:"token"`
+	Organization int    `json:"organization"`
+	Invitee      string `json:"invitee"`
+	Role         string `json:"role"`
+}
+
+// APIRouteOrganizationInvite is
+var APIRouteOrganizationInvite = Route{
+	"POST",
+	"/organization/invite",
+	APIRequestStructInviteOrganization{},
+	nil,
+	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
+		// Invitations are sent by mail and disabled without their template
+		if conf.TemplateInvite == 0 {
+			return nil, errNotEnabled
+		}
+
+		// Parse JSON request
+		var reqData APIRequestStructInviteOrganization
+		if err := checkJSONBody(req, res, &reqData); err != nil {
+			return nil, err
+		}
+
+		account, err := checkAccount(req, reqData.Address, reqData.Token)
+		if err != nil {
+			return nil, err
+		}
+
+		// Only owners can invite new members
+		_, err = checkMember(req, account, reqData.Organization, data.RoleOwner)
+		if err != nil {
+			return nil, err
+		}
+
+		role, ok := roles[reqData.Role]
+		if !ok {
+			return nil, errUnknownRole
+		}
+
+		invitee, err := data.ParseAddress(reqData.Invitee)
+		if err != nil {
+			return nil, errAddress("invitee", err)
+		}
+
+		org, err := data.OrganizationByIDContext(req.Context(), reqData.Organization)
+		if err != nil {
+			return nil, errUnknownOrganization
+		}
+
+		invitation := data.InvitationNew(org.ID, invitee, role)
+		invitationRaw := invitation.Raw()
+		invitation, err = invitation.StoreContext(req.Context())
+
+		if err != nil {
+			return nil, errInternal("Unable to create invitation").WithCause(err)
+		}
+
+		// Queue invitation mail
+		err = mail.Queue(invitee, conf.TemplateInvite, map[string]interface{}{
+			"token":        invitationRaw,
+			"organization": org.Name,
+			"role":         reqData.Role,
+			"from":         account.Address,
+		})
+
+		// If mail cannot be sent, fail and remove invitation
+		if err != nil {
+			invitation.RemoveContext(req.Context())
+			return nil, errInternal("Unable to send invitation mail").WithCause(err)
+		}
+
+		return nil, nil
+	},
+	nil,
+}
@@ -0,0 +1,69 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"net/http"
+
+	"github.com/clinotes/server/data"
+)
+
+// APIRequestStructJoinOrganization is
+type APIRequestStructJoinOrganization struct {
+	Address      string `json:"address"`
+	Token        string `json:"token"`
+	Organization int    `json:"organization"`
+	Invitation   string `json:"invitation"`
+}
+
+// APIRouteOrganizationJoin is
+var APIRouteOrganizationJoin = Route{
+	"POST",
+	"/organization/join",
+	APIRequestStructJoinOrganization{},
+	nil,
+	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
+		// Parse JSON request
+		var reqData APIRequestStructJoinOrganization
+		if err := checkJSONBody(req, res, &reqData); err != nil {
+			return nil, err
+		}
+
+		account, err := checkAccount(req, reqData.Address, reqData.Token)
+		if err != nil {
+			return nil, err
+		}
+
+		// Find invitation sent to the account address
+		for _, invitation := range data.InvitationListByOrganizationAndAddressContext(req.Context(), reqData.Organization, account.Address) {
+			if !invitation.Matches(reqData.Invitation) {
+				continue
+			}
+
+			if _, err = invitation.AcceptContext(req.Context(), account.ID); err != nil {
+				return nil, errInternal("Unable to join organization").WithCause(err)
+			}
+
+			return nil, nil
+		}
+
+		return nil, newError(http.StatusUnauthorized, "invalid_invitation", "Unable to use provided invitation")
+	},
+	nil,
+}
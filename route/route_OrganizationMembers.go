@@ -0,0 +1,83 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/clinotes/server/data"
+)
+
+// APIRequestStructOrganizationMembers is
+type APIRequestStructOrganizationMembers struct {
+	Address      string `json:"address"`
+	Token        string `json:"token"`
+	Organization int    `json:"organization"`
+}
+
+// APIResponseStructMember is
+type APIResponseStructMember struct {
+	Address string
+	Role    string
+	Created time.Time
+}
+
+// APIRouteOrganizationMembers is
+var APIRouteOrganizationMembers = Route{
+	"POST",
+	"/organization/members",
+	APIRequestStructOrganizationMembers{},
+	[]APIResponseStructMember{},
+	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
+		// Parse JSON request
+		var reqData APIRequestStructOrganizationMembers
+		if err := checkJSONBody(req, res, &reqData); err != nil {
+			return nil, err
+		}
+
+		account, err := checkAccount(req, reqData.Address, reqData.Token)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = checkMember(req, account, reqData.Organization, data.RoleViewer)
+		if err != nil {
+			return nil, err
+		}
+
+		list, err := data.MemberListByOrganizationContext(req.Context(), reqData.Organization)
+		if err != nil {
+			return nil, errInternal("Failed to get members").WithCause(err)
+		}
+
+		var memberList []APIResponseStructMember
+		for _, member := range list {
+			acc, err := data.AccountByIDContext(req.Context(), member.Account)
+			if err != nil {
+				return nil, errInternal("Failed to get members").WithCause(err)
+			}
+
+			memberList = append(memberList, APIResponseStructMember{acc.Address, roleName(member.Role), member.Created})
+		}
+
+		return memberList, nil
+	},
+	nil,
+}
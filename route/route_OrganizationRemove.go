@@ -0,0 +1,85 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"net/http"
+
+	"github.com/clinotes/server/data"
+)
+
+// APIRequestStructOrganizationRemove is
+type APIRequestStructOrganizationRemove struct {
+	Address      string `json:"address"`
+	Token        string `json:"token"`
+	Organization int    `json:"organization"`
+	Member       string `json:"member"`
+}
+
+// APIRouteOrganizationRemove is
+var APIRouteOrganizationRemove = Route{
+	"POST",
+	"/organization/remove",
+	APIRequestStructOrganizationRemove{},
+	nil,
+	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
+		// Parse JSON request
+		var reqData APIRequestStructOrganizationRemove
+		if err := checkJSONBody(req, res, &reqData); err != nil {
+			return nil, err
+		}
+
+		account, err := checkAccount(req, reqData.Address, reqData.Token)
+		if err != nil {
+			return nil, err
+		}
+
+		// Members can leave, owners can remove everybody
+		role := data.RoleOwner
+		if data.NormalizeAddress(reqData.Member) == data.NormalizeAddress(account.Address) {
+			role = data.RoleViewer
+		}
+
+		_, err = checkMember(req, account, reqData.Organization, role)
+		if err != nil {
+			return nil, err
+		}
+
+		memberAccount, err := data.AccountByAddressContext(req.Context(), reqData.Member)
+		if err != nil {
+			return nil, errUnknownMember
+		}
+
+		member, err := data.MemberByOrganizationAndAccountContext(req.Context(), reqData.Organization, memberAccount.ID)
+		if err != nil {
+			return nil, errUnknownMember
+		}
+
+		if member.Role == data.RoleOwner && !hasOtherOwner(req.Context(), member) {
+			return nil, errConflict("last_owner", "Organization needs at least one owner")
+		}
+
+		if err = member.RemoveContext(req.Context()); err != nil {
+			return nil, errInternal("Unable to remove member").WithCause(err)
+		}
+
+		return nil, nil
+	},
+	nil,
+}
@@ -0,0 +1,106 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/clinotes/server/data"
+)
+
+// APIRequestStructOrganizationRole is
+type APIRequestStructOrganizationRole struct {
+	Address      string `json:"address"`
+	Token        string `json:"token"`
+	Organization int    `json:"organization"`
+	Member       string `json:"member"`
+	Role         string `json:"role"`
+}
+
+// APIRouteOrganizationRole is
+var APIRouteOrganizationRole = Route{
+	"POST",
+	"/organization/role",
+	APIRequestStructOrganizationRole{},
+	nil,
+	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
+		// Parse JSON request
+		var reqData APIRequestStructOrganizationRole
+		if err := checkJSONBody(req, res, &reqData); err != nil {
+			return nil, err
+		}
+
+		account, err := checkAccount(req, reqData.Address, reqData.Token)
+		if err != nil {
+			return nil, err
+		}
+
+		// Only owners can change roles
+		_, err = checkMember(req, account, reqData.Organization, data.RoleOwner)
+		if err != nil {
+			return nil, err
+		}
+
+		role, ok := roles[reqData.Role]
+		if !ok {
+			return nil, errUnknownRole
+		}
+
+		memberAccount, err := data.AccountByAddressContext(req.Context(), reqData.Member)
+		if err != nil {
+			return nil, errUnknownMember
+		}
+
+		member, err := data.MemberByOrganizationAndAccountContext(req.Context(), reqData.Organization, memberAccount.ID)
+		if err != nil {
+			return nil, errUnknownMember
+		}
+
+		if member.Role == data.RoleOwner && role != data.RoleOwner && !hasOtherOwner(req.Context(), member) {
+			return nil, errConflict("last_owner", "Organization needs at least one owner")
+		}
+
+		member.Role = role
+		_, err = member.StoreContext(req.Context())
+
+		if err != nil {
+			return nil, errInternal("Unable to change role").WithCause(err)
+		}
+
+		return nil, nil
+	},
+	nil,
+}
+
+// hasOtherOwner checks if the organization of member has another owner
+func hasOtherOwner(ctx context.Context, member *data.Member) bool {
+	list, err := data.MemberListByOrganizationContext(ctx, member.Organization)
+	if err != nil {
+		return false
+	}
+
+	for _, item := range list {
+		if item.ID != member.ID && item.Role == data.RoleOwner {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,78 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/clinotes/server/data"
+)
+
+// APIRequestStructOrganizations is
+type APIRequestStructOrganizations struct {
+	Address string `json:"address"`
+	Token   string `json:"token"`
+}
+
+// APIResponseStructOrganization is
+type APIResponseStructOrganization struct {
+	ID      int
+	Name    string
+	Role    string
+	Created time.Time
+}
+
+// APIRouteOrganizations is
+var APIRouteOrganizations = Route{
+	"POST",
+	"/organizations",
+	APIRequestStructOrganizations{},
+	[]APIResponseStructOrganization{},
+	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
+		// Parse JSON request
+		var reqData APIRequestStructOrganizations
+		if err := checkJSONBody(req, res, &reqData); err != nil {
+			return nil, err
+		}
+
+		account, err := checkAccount(req, reqData.Address, reqData.Token)
+		if err != nil {
+			return nil, err
+		}
+
+		list, err := data.OrganizationListByAccountContext(req.Context(), account.ID)
+		if err != nil {
+			return nil, errInternal("Failed to get organizations").WithCause(err)
+		}
+
+		var orgList []APIResponseStructOrganization
+		for _, org := range list {
+			member, err := org.GetMemberContext(req.Context(), account.ID)
+			if err != nil {
+				return nil, errInternal("Failed to get organizations").WithCause(err)
+			}
+
+			orgList = append(orgList, APIResponseStructOrganization{org.ID, org.Name, roleName(member.Role), org.Created})
+		}
+
+		return orgList, nil
+	},
+	nil,
+}
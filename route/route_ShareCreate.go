@@ -0,0 +1,112 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/clinotes/server/data"
+)
+
+// APIRequestStructCreateShare is
+type APIRequestStructCreateShare struct {
+	Address string `json:"address"`
+	Token   string `json:"token"`
+	Note    int    `json:"note"`
+	Expires int    `json:"expires"`
+	Views   int    `json:"views"`
+}
+
+// APIResponseStructShare is
+type APIResponseStructShare struct {
+	ID      int
+	Note    int
+	URL     string
+	Created time.Time
+	Expires *time.Time
+	Views   int
+	Limit   int
+}
+
+// APIRouteShareCreate is
+var APIRouteShareCreate = Route{
+	"POST",
+	"/share/create",
+	APIRequestStructCreateShare{},
+	APIResponseStructShare{},
+	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
+		// Parse JSON request
+		var reqData APIRequestStructCreateShare
+		if err := checkJSONBody(req, res, &reqData); err != nil {
+			return nil, err
+		}
+
+		account, err := checkAccount(req, reqData.Address, reqData.Token)
+		if err != nil {
+			return nil, err
+		}
+
+		// Sharing a note needs write permission
+		note, err := checkNote(req, account, reqData.Note, data.RoleEditor)
+		if err != nil {
+			return nil, err
+		}
+
+		if reqData.Expires < 0 || reqData.Views < 0 {
+			return nil, errInvalid("invalid_limits", "Invalid share limits")
+		}
+
+		// Expiry is given in seconds from now
+		var expires *time.Time
+		if reqData.Expires > 0 {
+			end := time.Now().Add(time.Duration(reqData.Expires) * time.Second)
+			expires = &end
+		}
+
+		share := data.ShareNew(note.ID, account.ID, expires, reqData.Views)
+		shareRaw := share.Raw()
+		share, err = share.StoreContext(req.Context())
+
+		if err != nil {
+			return nil, errInternal("Unable to share note").WithCause(err)
+		}
+
+		return APIResponseStructShare{
+			share.ID,
+			share.Note,
+			shareURL(req, shareRaw),
+			share.Created,
+			share.Expires,
+			share.Views,
+			share.MaxViews,
+		}, nil
+	},
+	nil,
+}
+
+// shareURL returns the public URL for a Share token
+func shareURL(req *http.Request, raw string) string {
+	scheme := "http"
+	if req.TLS != nil || req.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	return scheme + "://" + req.Host + "/s/" + raw
+}
@@ -0,0 +1,64 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"net/http"
+
+	"github.com/clinotes/server/data"
+)
+
+// APIRequestStructRevokeShare is
+type APIRequestStructRevokeShare struct {
+	Address string `json:"address"`
+	Token   string `json:"token"`
+	Share   int    `json:"share"`
+}
+
+// APIRouteShareRevoke is
+var APIRouteShareRevoke = Route{
+	"POST",
+	"/share/revoke",
+	APIRequestStructRevokeShare{},
+	nil,
+	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
+		// Parse JSON request
+		var reqData APIRequestStructRevokeShare
+		if err := checkJSONBody(req, res, &reqData); err != nil {
+			return nil, err
+		}
+
+		account, err := checkAccount(req, reqData.Address, reqData.Token)
+		if err != nil {
+			return nil, err
+		}
+
+		share, err := data.ShareByIDContext(req.Context(), reqData.Share)
+		if err != nil || share.Account != account.ID {
+			return nil, errNotFound("unknown_share", "Unknown share")
+		}
+
+		if err = share.RemoveContext(req.Context()); err != nil {
+			return nil, errInternal("Unable to revoke share").WithCause(err)
+		}
+
+		return nil, nil
+	},
+	nil,
+}
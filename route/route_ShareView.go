@@ -0,0 +1,82 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/clinotes/server/data"
+	"github.com/gorilla/mux"
+)
+
+var shareTemplate = template.Must(template.New("share").Parse(`<!DOCTYPE html>
+<html>
+  <head>
+    <meta charset="utf-8" />
+    <meta name="viewport" content="width=device-width, initial-scale=1.0" />
+    <meta name="robots" content="noindex" />
+    <title>CLINotes</title>
+  </head>
+  <body>
+    <p>{{.Text}}</p>
+    <p><small>{{.Created.Format "January 2, 2006 15:04"}}</small></p>
+  </body>
+</html>
+`))
+
+// ShareView renders a shared Note without authentication, as HTML for
+// browsers and as plain text otherwise
+func ShareView(res http.ResponseWriter, req *http.Request) {
+	share, err := data.ShareByRawContext(req.Context(), mux.Vars(req)["token"])
+	if err != nil {
+		http.NotFound(res, req)
+		return
+	}
+
+	share, err = share.ViewContext(req.Context())
+	if err != nil {
+		http.NotFound(res, req)
+		return
+	}
+
+	note, err := data.NoteByIDContext(req.Context(), share.Note)
+	if err != nil {
+		http.NotFound(res, req)
+		return
+	}
+
+	res.Header().Set("Cache-Control", "no-store")
+
+	format := req.URL.Query().Get("format")
+	if format == "" && strings.Contains(req.Header.Get("Accept"), "text/html") {
+		format = "html"
+	}
+
+	if format == "html" {
+		res.Header().Set("Content-Type", "text/html; charset=utf-8")
+		shareTemplate.Execute(res, note)
+		return
+	}
+
+	res.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	res.Write([]byte(note.Text + "\n\n" + note.Created.Format(time.RFC3339) + "\n"))
+}
@@ -0,0 +1,73 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"net/http"
+
+	"github.com/clinotes/server/data"
+)
+
+// APIRequestStructShares is
+type APIRequestStructShares struct {
+	Address string `json:"address"`
+	Token   string `json:"token"`
+}
+
+// APIRouteShares is
+var APIRouteShares = Route{
+	"POST",
+	"/shares",
+	APIRequestStructShares{},
+	[]APIResponseStructShare{},
+	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
+		// Parse JSON request
+		var reqData APIRequestStructShares
+		if err := checkJSONBody(req, res, &reqData); err != nil {
+			return nil, err
+		}
+
+		account, err := checkAccount(req, reqData.Address, reqData.Token)
+		if err != nil {
+			return nil, err
+		}
+
+		list, err := data.ShareListByAccountContext(req.Context(), account.ID)
+		if err != nil {
+			return nil, errInternal("Failed to get shares").WithCause(err)
+		}
+
+		// Tokens are only known on creation, so no URL can be listed
+		var shareList []APIResponseStructShare
+		for _, share := range list {
+			shareList = append(shareList, APIResponseStructShare{
+				share.ID,
+				share.Note,
+				"",
+				share.Created,
+				share.Expires,
+				share.Views,
+				share.MaxViews,
+			})
+		}
+
+		return shareList, nil
+	},
+	nil,
+}
@@ -19,10 +19,8 @@
 package route
 
 import (
-	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"strings"
 
 	"github.com/clinotes/server/data"
@@ -43,7 +41,10 @@ type APIRequestStructSubscribe struct {
 
 // APIRouteSubscribe is
 var APIRouteSubscribe = Route{
+	"POST",
 	"/subscribe",
+	APIRequestStructSubscribe{},
+	nil,
 	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
 		// Parse JSON request
 		var reqData APIRequestStructSubscribe
@@ -52,22 +53,27 @@ var APIRouteSubscribe = Route{
 		}
 
 		// Get account
-		account, err := data.AccountByAddress(reqData.Address)
+		account, err := accountByAddress(req, reqData.Address)
 		if err != nil {
-			return nil, errors.New("Unknown account address")
+			return nil, authFailed(err)
 		}
 
 		if !account.Verified {
-			return nil, errors.New("Account not verified")
+			return nil, authFailed(errNotVerified)
 		}
 
 		// Check if account has requested token
-		_, err = account.GetToken(reqData.Token, data.TokenTypeAccess)
+		_, err = verifyToken(req, account, reqData.Token, data.TokenTypeAccess)
 		if err != nil {
-			return nil, errors.New("Unable to use provided token")
+			return nil, authFailed(err)
 		}
 
-		stripe.Key = os.Getenv("STRIPE_API_KEY")
+		account, err = stripeCustomerOf(req, account)
+		if err != nil {
+			return nil, err
+		}
+
+		stripe.Key = conf.StripeKey
 		expire := strings.Split(reqData.Expire, "/")
 		t, err := stripeToken.New(&stripe.TokenParams{
 			Card: &stripe.CardParams{
@@ -79,17 +85,31 @@ var APIRouteSubscribe = Route{
 		})
 
 		if err != nil {
-			return nil, errors.New("Invalid card information")
+			return nil, errInvalid("invalid_card", "Invalid card information")
 		}
 
 		customerParams := &stripe.CustomerParams{
 			Desc: fmt.Sprintf("%s (#%d)", account.Address, account.ID),
 		}
 		customerParams.SetSource(t.ID)
-		c, err := stripeCustomer.New(customerParams)
+
+		// Reuse the Stripe customer of the account if there is one
+		var c *stripe.Customer
+		if account.Customer != "" {
+			c, err = stripeCustomer.Update(account.Customer, customerParams)
+		} else {
+			c, err = stripeCustomer.New(customerParams)
+		}
+
+		if err != nil {
+			return nil, errInternal("Invalid account information").WithCause(err)
+		}
+
+		account.Customer = c.ID
+		account, err = account.StoreContext(req.Context())
 
 		if err != nil {
-			return nil, errors.New("Invalid account information")
+			return nil, errInternal("Invalid account information").WithCause(err)
 		}
 
 		s, err := stripeSub.New(&stripe.SubParams{
@@ -98,17 +118,30 @@ var APIRouteSubscribe = Route{
 		})
 
 		if err != nil {
-			return nil, errors.New("Invalid account information")
+			return nil, errInternal("Invalid account information").WithCause(err)
+		}
+
+		// Replace a running trial with the paid Subscription
+		if trial := account.GetSubscriptionContext(req.Context()); trial != nil && trial.IsTrial() {
+			if _, err = trial.DeactivateContext(req.Context()); err != nil {
+				return nil, errInternal("Unable to end trial").WithCause(err)
+			}
 		}
 
 		subscription := data.SubscriptionNew(account.ID, s.ID)
-		subscription, err = subscription.Store()
-		subscription, err = subscription.Activate()
+		subscription, err = subscription.StoreContext(req.Context())
+
+		if err != nil {
+			return nil, errInternal("Invalid account information").WithCause(err)
+		}
+
+		subscription, err = subscription.ActivateContext(req.Context())
 
 		if err != nil {
-			return nil, errors.New("Invalid account information")
+			return nil, errInternal("Invalid account information").WithCause(err)
 		}
 
 		return nil, nil
 	},
+	nil,
 }
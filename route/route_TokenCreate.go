@@ -19,7 +19,6 @@
 package route
 
 import (
-	"errors"
 	"net/http"
 
 	"github.com/clinotes/server/data"
@@ -32,7 +31,10 @@ type APIRequestStructCreateToken struct {
 
 // APIRouteTokenCreate is
 var APIRouteTokenCreate = Route{
+	"POST",
 	"/token/create",
+	APIRequestStructCreateToken{},
+	nil,
 	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
 		// Parse JSON request
 		var reqData APIRequestStructCreateToken
@@ -41,25 +43,29 @@ var APIRouteTokenCreate = Route{
 		}
 
 		// Get account
-		account, err := data.AccountByAddress(reqData.Address)
+		account, err := accountByAddress(req, reqData.Address)
 		if err != nil {
-			return nil, errors.New("Unknown account address")
+			return nil, err
 		}
 
 		if !account.Verified {
-			return nil, errors.New("Account not verified")
+			return nil, authFailed(errNotVerified)
 		}
 
 		token := data.TokenNew(account.ID, data.TokenTypeAccess)
 		tokenRaw := token.Raw()
-		token, err = token.Store()
+		token, err = token.StoreContext(req.Context())
+		if err != nil {
+			return nil, errInternal("Unable to create token for account").WithCause(err)
+		}
 
-		_, err = sendTokenWithTemplate(reqData.Address, tokenRaw, conf.TemplateToken)
+		err = queueTokenWithTemplate(reqData.Address, tokenRaw, conf.TemplateToken)
 		if err != nil {
-			token.Remove()
-			return nil, errors.New("Unable to create token for account")
+			token.RemoveContext(req.Context())
+			return nil, errInternal("Unable to create token for account").WithCause(err)
 		}
 
 		return nil, nil
 	},
+	nil,
 }
@@ -0,0 +1,54 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"net/http"
+	"time"
+)
+
+// APIResponseStructV1Account is
+type APIResponseStructV1Account struct {
+	Address      string    `json:"address"`
+	Created      time.Time `json:"created"`
+	Verified     bool      `json:"verified"`
+	Subscription bool      `json:"subscription"`
+}
+
+// APIRouteV1Account returns the authenticated account
+var APIRouteV1Account = Route{
+	"GET",
+	"/account",
+	nil,
+	APIResponseStructV1Account{},
+	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
+		account, _, err := checkBasicAuth(req)
+		if err != nil {
+			return nil, err
+		}
+
+		return APIResponseStructV1Account{
+			account.Address,
+			account.Created,
+			account.Verified,
+			account.HasSubscriptionContext(req.Context()),
+		}, nil
+	},
+	nil,
+}
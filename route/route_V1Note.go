@@ -0,0 +1,157 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"net/http"
+
+	"github.com/clinotes/server/data"
+)
+
+// APIRequestStructV1NoteUpdate is
+type APIRequestStructV1NoteUpdate struct {
+	Text string `json:"text"`
+}
+
+// APIRouteV1Note returns a single note
+var APIRouteV1Note = Route{
+	"GET",
+	"/notes/{id}",
+	nil,
+	APIResponseStructV1Note{},
+	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
+		account, _, err := checkBasicAuth(req)
+		if err != nil {
+			return nil, err
+		}
+
+		note, err := checkNote(req, account, pathID(req), data.RoleViewer)
+		if err != nil {
+			return nil, err
+		}
+
+		return v1Note(note), nil
+	},
+	nil,
+}
+
+// APIRouteV1NoteUpdate changes the text of a note
+var APIRouteV1NoteUpdate = Route{
+	"PATCH",
+	"/notes/{id}",
+	APIRequestStructV1NoteUpdate{},
+	APIResponseStructV1Note{},
+	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
+		account, accessToken, err := checkBasicAuth(req)
+		if err != nil {
+			return nil, err
+		}
+
+		note, err := checkNote(req, account, pathID(req), data.RoleEditor)
+		if err != nil {
+			return nil, err
+		}
+
+		var reqData APIRequestStructV1NoteUpdate
+		if err = checkJSONBody(req, res, &reqData); err != nil {
+			return nil, err
+		}
+
+		note.Text = reqData.Text
+		if err = note.Validate(); err != nil {
+			return nil, errInvalid("invalid_note", "Invalid note").WithDetail("text", err.Error())
+		}
+
+		note, err = note.StoreWithTokenContext(req.Context(), accessToken.ID)
+		if err != nil {
+			return nil, errInternal("Unable to store note").WithCause(err)
+		}
+
+		return v1Note(note), nil
+	},
+	nil,
+}
+
+// APIRouteV1NoteDelete removes a note with its history
+var APIRouteV1NoteDelete = Route{
+	"DELETE",
+	"/notes/{id}",
+	nil,
+	nil,
+	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
+		account, _, err := checkBasicAuth(req)
+		if err != nil {
+			return nil, err
+		}
+
+		note, err := checkNote(req, account, pathID(req), data.RoleEditor)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = note.RemoveContext(req.Context()); err != nil {
+			return nil, errInternal("Unable to remove note").WithCause(err)
+		}
+
+		return nil, nil
+	},
+	nil,
+}
+
+// APIRouteV1NoteRevisions lists the history of a note
+var APIRouteV1NoteRevisions = Route{
+	"GET",
+	"/notes/{id}/revisions",
+	nil,
+	[]APIResponseStructRevision{},
+	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
+		account, _, err := checkBasicAuth(req)
+		if err != nil {
+			return nil, err
+		}
+
+		note, err := checkNote(req, account, pathID(req), data.RoleViewer)
+		if err != nil {
+			return nil, err
+		}
+
+		list, err := note.GetRevisionListContext(req.Context())
+		if err != nil {
+			return nil, errInternal("Failed to get note history").WithCause(err)
+		}
+
+		// Every revision carries the changes to its predecessor
+		revisionList := []APIResponseStructRevision{}
+		var previous *data.Revision
+		for _, revision := range list {
+			revisionList = append(revisionList, APIResponseStructRevision{
+				revision.ID,
+				revision.Text,
+				revision.Created,
+				revision.Token,
+				revision.Diff(previous),
+			})
+
+			previous = revision
+		}
+
+		return revisionList, nil
+	},
+	nil,
+}
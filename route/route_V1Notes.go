@@ -0,0 +1,131 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/clinotes/server/data"
+)
+
+// APIRequestStructV1Note is
+type APIRequestStructV1Note struct {
+	Text         string `json:"text"`
+	Organization int    `json:"organization"`
+}
+
+// APIResponseStructV1Note is
+type APIResponseStructV1Note struct {
+	ID           int       `json:"id"`
+	Text         string    `json:"text"`
+	Organization int       `json:"organization,omitempty"`
+	Created      time.Time `json:"created"`
+}
+
+func v1Note(note *data.Note) APIResponseStructV1Note {
+	return APIResponseStructV1Note{note.ID, note.Text, note.Organization, note.Created}
+}
+
+// APIRouteV1Notes lists personal notes or notes of the organization passed
+// as query parameter
+var APIRouteV1Notes = Route{
+	"GET",
+	"/notes",
+	nil,
+	[]APIResponseStructV1Note{},
+	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
+		account, _, err := checkBasicAuth(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var list []data.Note
+
+		if value := req.URL.Query().Get("organization"); value != "" {
+			organization, convErr := strconv.Atoi(value)
+			if convErr != nil {
+				return nil, errUnknownOrganization
+			}
+
+			if _, err = checkMember(req, account, organization, data.RoleViewer); err != nil {
+				return nil, err
+			}
+
+			list, err = data.NoteListByOrganizationContext(req.Context(), organization)
+		} else {
+			list, err = data.NoteListByAccountContext(req.Context(), account.ID)
+		}
+
+		if err != nil {
+			return nil, errInternal("Failed to get notes").WithCause(err)
+		}
+
+		noteList := []APIResponseStructV1Note{}
+		for i := range list {
+			noteList = append(noteList, v1Note(&list[i]))
+		}
+
+		return noteList, nil
+	},
+	nil,
+}
+
+// APIRouteV1NoteCreate creates a personal note or a note of an organization
+var APIRouteV1NoteCreate = Route{
+	"POST",
+	"/notes",
+	APIRequestStructV1Note{},
+	APIResponseStructV1Note{},
+	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
+		account, accessToken, err := checkBasicAuth(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var reqData APIRequestStructV1Note
+		if err = checkJSONBody(req, res, &reqData); err != nil {
+			return nil, err
+		}
+
+		note := data.NoteNew(account.ID, reqData.Text)
+
+		// Notes of an organization can be added by editors
+		if reqData.Organization != 0 {
+			if _, err = checkMember(req, account, reqData.Organization, data.RoleEditor); err != nil {
+				return nil, err
+			}
+
+			note = data.NoteOrganizationNew(reqData.Organization, account.ID, reqData.Text)
+		}
+
+		if err = note.Validate(); err != nil {
+			return nil, errInvalid("invalid_note", "Invalid note").WithDetail("text", err.Error())
+		}
+
+		note, err = note.StoreWithTokenContext(req.Context(), accessToken.ID)
+		if err != nil {
+			return nil, errInternal("Unable to store note").WithCause(err)
+		}
+
+		return v1Note(note), nil
+	},
+	nil,
+}
@@ -0,0 +1,66 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package route
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/clinotes/server/data"
+)
+
+// APIResponseStructV1Organization is
+type APIResponseStructV1Organization struct {
+	ID      int       `json:"id"`
+	Name    string    `json:"name"`
+	Role    string    `json:"role"`
+	Created time.Time `json:"created"`
+}
+
+// APIRouteV1Organizations lists organizations of the account
+var APIRouteV1Organizations = Route{
+	"GET",
+	"/organizations",
+	nil,
+	[]APIResponseStructV1Organization{},
+	func(res http.ResponseWriter, req *http.Request) (interface{}, error) {
+		account, _, err := checkBasicAuth(req)
+		if err != nil {
+			return nil, err
+		}
+
+		list, err := data.OrganizationListByAccountContext(req.Context(), account.ID)
+		if err != nil {
+			return nil, errInternal("Failed to get organizations").WithCause(err)
+		}
+
+		orgList := []APIResponseStructV1Organization{}
+		for _, org := range list {
+			member, err := org.GetMemberContext(req.Context(), account.ID)
+			if err != nil {
+				return nil, errInternal("Failed to get organizations").WithCause(err)
+			}
+
+			orgList = append(orgList, APIResponseStructV1Organization{org.ID, org.Name, roleName(member.Role), org.Created})
+		}
+
+		return orgList, nil
+	},
+	nil,
+}
@@ -0,0 +1,131 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package schedule
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/clinotes/server/data"
+	"github.com/clinotes/server/logger"
+	"github.com/clinotes/server/mail"
+	stripeSub "github.com/stripe/stripe-go/sub"
+)
+
+// trialReminder is the time before the end of a trial to send a reminder
+const trialReminder = 3 * 24 * time.Hour
+
+// JobSubscriptions reminds accounts of ending trials and failed payments and
+// downgrades them once the trial or grace period has passed
+var JobSubscriptions = Job(func() error {
+	list, err := data.SubscriptionListActive()
+	if err != nil {
+		return err
+	}
+
+	// A failing subscription must not hold up the others
+	failed := 0
+	for _, sub := range list {
+		if sub.IsTrial() {
+			err = checkTrial(sub)
+		} else {
+			err = checkPayment(sub)
+		}
+
+		if err != nil {
+			failed++
+			logger.Error("Failed to check subscription", logger.Fields{"subscription": sub.ID, "error": err})
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("Failed to check %d of %d subscriptions", failed, len(list))
+	}
+
+	return nil
+})
+
+func checkTrial(sub *data.Subscription) error {
+	if !sub.InTrial() {
+		_, err := sub.Deactivate()
+		return err
+	}
+
+	if sub.Reminded != nil || time.Until(*sub.TrialEnd) > trialReminder {
+		return nil
+	}
+
+	return remind(sub, conf.TemplateTrial, *sub.TrialEnd)
+}
+
+func checkPayment(sub *data.Subscription) error {
+	if sub.GraceEnd != nil && !sub.InGrace() {
+		_, err := sub.Deactivate()
+		return err
+	}
+
+	// Without Stripe there is no way to know about failed payments
+	if conf.StripeKey == "" {
+		return nil
+	}
+
+	s, err := stripeSub.Get(sub.StripeID, nil)
+	if err != nil {
+		return err
+	}
+
+	switch s.Status {
+	case "active", "trialing":
+		_, err = sub.EndGrace()
+		return err
+	}
+
+	if sub.GraceEnd != nil {
+		return nil
+	}
+
+	sub, err = sub.StartGrace(time.Now().Add(conf.GracePeriod))
+	if err != nil {
+		return err
+	}
+
+	return remind(sub, conf.TemplateGrace, *sub.GraceEnd)
+}
+
+func remind(sub *data.Subscription, template int64, end time.Time) error {
+	if template <= 0 {
+		return nil
+	}
+
+	account, err := data.AccountByID(sub.Account)
+	if err != nil {
+		return err
+	}
+
+	err = mail.Send(account.Address, template, map[string]interface{}{
+		"expires": end.Format("January 2, 2006"),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	_, err = sub.Remind()
+	return err
+}
@@ -0,0 +1,55 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package schedule
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/clinotes/server/data"
+	"github.com/clinotes/server/logger"
+)
+
+// JobUnverifiedAccounts removes accounts which have not been verified within
+// the configured period, so their addresses can sign up again
+var JobUnverifiedAccounts = Job(func() error {
+	if conf.UnverifiedPeriod <= 0 {
+		return nil
+	}
+
+	list, err := data.AccountListUnverified(time.Now().Add(-conf.UnverifiedPeriod))
+	if err != nil {
+		return err
+	}
+
+	// A failing account must not hold up the others
+	failed := 0
+	for _, account := range list {
+		if err = account.Remove(); err != nil {
+			failed++
+			logger.Error("Failed to remove unverified account", logger.Fields{"account": account.ID, "error": err})
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("Failed to remove %d of %d unverified accounts", failed, len(list))
+	}
+
+	return nil
+})
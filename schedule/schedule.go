@@ -0,0 +1,99 @@
+/**
+ * clinot.es server
+ * Copyright (C) 2016 Sebastian Müller
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package schedule
+
+import (
+	"time"
+
+	"github.com/clinotes/server/logger"
+	stripe "github.com/stripe/stripe-go"
+)
+
+// Job is a task run periodically in the background
+type Job func() error
+
+var (
+	conf Configuration
+	quit chan struct{}
+	done chan struct{}
+)
+
+// Configuration stores need variables
+type Configuration struct {
+	Interval         time.Duration
+	GracePeriod      time.Duration
+	UnverifiedPeriod time.Duration
+
+	TemplateTrial int64
+	TemplateGrace int64
+
+	StripeKey string
+}
+
+// Jobs returns available jobs
+func Jobs() []Job {
+	return []Job{
+		JobSubscriptions,
+		JobUnverifiedAccounts,
+	}
+}
+
+// Start runs all jobs in the background every configured interval
+func Start(config Configuration) {
+	conf = config
+	stripe.Key = config.StripeKey
+
+	quit = make(chan struct{})
+	done = make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(conf.Interval)
+		defer ticker.Stop()
+		defer close(done)
+
+		for {
+			run()
+
+			select {
+			case <-ticker.C:
+			case <-quit:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the background jobs and waits for a running job to finish
+func Stop() {
+	if quit == nil {
+		return
+	}
+
+	close(quit)
+	<-done
+	quit = nil
+}
+
+func run() {
+	for _, job := range Jobs() {
+		if err := job(); err != nil {
+			logger.Error("Failed to run scheduled job", logger.Fields{"error": err})
+		}
+	}
+}